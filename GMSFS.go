@@ -1,8 +1,12 @@
 package GMSFS
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
@@ -11,9 +15,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/dgraph-io/ristretto"
+	cmap "github.com/orcaman/concurrent-map/v2"
 )
 
 // FileInfo stores comprehensive metadata about a file or directory
@@ -26,11 +33,15 @@ type FileInfo struct {
 	Contents     []FileInfo // Names of files for directories
 	Name         string
 	CacheTime    time.Time
+	SHA256       string // Hex checksum of the contents, empty until computed
+	Allocated    int64  // Bytes reserved by Preallocate, Size stays the written length
+	ContentType  string // MIME type, empty until ContentType is called
 }
 
 type CachedFile struct {
 	*os.File
-	path string
+	path   string
+	direct bool
 }
 
 const timeFlat = "20060102_1504"
@@ -42,20 +53,39 @@ type FileHandleInstance struct {
 }
 
 type CacheItem struct {
-	Value     interface{}
-	Timestamp time.Time
+	Key        string
+	Value      interface{}
+	Timestamp  time.Time
+	Revalidate bool          // Loaded from a saved cache, check against disk on first use
+	Listed     bool          // Directory listing is known, see Children
+	Children   []string      // Keys of the directory entries, resolved into Contents on read
+	ChildModes []os.FileMode // Modes of Children as listed, not following symlinks
+	Generation uint64        // Changes whenever the entry is stored again
 }
 
 var cache = initCache()
+
+// cacheKeys tracks the keys stored in the cache, as ristretto cannot be iterated
+var cacheKeys = cmap.New[struct{}]()
+var cacheHits, cacheMisses uint64
 var MaxCacheTime = 300 * time.Second
 var MacCacheDirDepth = 4
 
+// MaxStaleTime enables stale-while-revalidate: an entry expired for less than
+// MaxStaleTime is still served while it is refreshed in the background
+var MaxStaleTime time.Duration
+
+// NegativeCacheTime is how long a "does not exist" entry is trusted, kept short
+// so newly created files are not masked for MaxCacheTime
+var NegativeCacheTime = 10 * time.Second
+
+// SoftCacheTime enables mtime based revalidation: an entry older than
+// SoftCacheTime is checked with a single os.Stat and kept (with its listing and
+// checksum) as long as mtime and size did not change
+var SoftCacheTime time.Duration
+
 func initCache() (cache *ristretto.Cache[string, CacheItem]) {
-	cache, err := ristretto.NewCache[string, CacheItem](&ristretto.Config[string, CacheItem]{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
-	})
+	cache, err := newCache(DefaultOptions)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -63,34 +93,146 @@ func initCache() (cache *ristretto.Cache[string, CacheItem]) {
 }
 
 func CacheAdd(key string, value FileInfo) {
-	// set a value with a cost of 1
-	ks := strings.Split(key, "/")
-	//	if len(ks) > MacCacheDirDepth {
+	item := CacheItem{Value: value, Timestamp: clockNow()}
+	if value.IsDir && value.Contents != nil && !caseCollision(value.Contents) {
+		// Children live under their own keys, the directory only references them
+		item.Children, item.ChildModes = cacheChildren(key, value)
+		item.Listed = true
+		value.Contents = nil
+		item.Value = value
+	}
+	cacheSet(key, item)
+}
+
+func cacheSet(key string, item CacheItem) {
+	//	if len(strings.Split(key, "/")) > MacCacheDirDepth {
 	//		return
 	//	}
-	cache.Set(key, CacheItem{Value: value, Timestamp: time.Now()}, int64(len(ks)))
+	if cachingDisabled(key) {
+		return
+	}
+	item.Key = key
+	item.Generation = atomic.AddUint64(&cacheGeneration, 1)
+	cache.Set(key, item, cacheCost(item))
+	cacheKeys.Set(key, struct{}{})
+}
+
+// onEvict runs when ristretto evicts an entry to make room. Children of an
+// evicted directory are dropped too, so nothing stays cached below a parent
+// the cache no longer knows about.
+func onEvict(item *ristretto.Item[CacheItem]) {
+	key := item.Value.Key
+	cacheKeys.Remove(key)
+
+	if value, ok := item.Value.Value.(FileInfo); ok && value.IsDir {
+		// Deleting from inside the eviction callback could block ristretto,
+		// so the children are dropped by evictWorker
+		select {
+		case evictedDirs <- key:
+		default:
+			go func() { evictedDirs <- key }()
+		}
+	}
+}
+
+var evictedDirs = make(chan string, 1024)
+
+func init() {
+	go evictWorker()
+}
+
+func evictWorker() {
+	for dir := range evictedDirs {
+		evictChildren(dir)
+	}
+}
+
+func evictChildren(dir string) {
+	prefix := strings.TrimSuffix(dir, string(os.PathSeparator)) + string(os.PathSeparator)
+	for _, k := range cacheKeys.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			CacheDelete(k)
+		}
+	}
 }
 
 func CacheGet(key string) (FileInfo, bool) {
 	item, found := cache.Get(key)
 	if !found {
+		atomic.AddUint64(&cacheMisses, 1)
 		return FileInfo{}, false
 	}
 	value := item.Value.(FileInfo) // Type assert to FileInfo
-	// Check if the item has expired
-	if time.Since(value.CacheTime) > MaxCacheTime {
+	if !value.Exists && clockSince(value.CacheTime) > NegativeCacheTime {
 		CacheDelete(key)
+		atomic.AddUint64(&cacheMisses, 1)
 		return FileInfo{}, false
 	}
+	if SoftCacheTime > 0 && clockSince(value.CacheTime) > SoftCacheTime {
+		if !revalidateEntry(key, value) {
+			CacheDelete(key)
+			atomic.AddUint64(&cacheMisses, 1)
+			return FileInfo{}, false
+		}
+		value.CacheTime = clockNow()
+		item.Value = value
+		item.Timestamp = value.CacheTime
+		cacheSet(key, item)
+	}
+	// Check if the item has expired
+	if ttl, age := cacheTTL(key), clockSince(value.CacheTime); age > ttl {
+		if MaxStaleTime <= 0 || age > ttl+MaxStaleTime {
+			CacheDelete(key)
+			atomic.AddUint64(&cacheMisses, 1)
+			return FileInfo{}, false
+		}
+		revalidateInBackground(key, value)
+	}
+	// Entries restored by LoadCache are only trusted once mtime and size match
+	if item.Revalidate {
+		if !revalidateEntry(key, value) {
+			CacheDelete(key)
+			atomic.AddUint64(&cacheMisses, 1)
+			return FileInfo{}, false
+		}
+		item.Revalidate = false
+		item.Timestamp = clockNow()
+		cacheSet(key, item)
+	}
+	if item.Listed {
+		value = resolveChildren(item, value)
+	}
+	atomic.AddUint64(&cacheHits, 1)
 	return value, true
 }
 
 func CacheDelete(key string) {
 	cache.Del(key)
+	cacheKeys.Remove(key)
+}
+
+// CacheFlush drops every entry from the cache
+func CacheFlush() {
+	cache.Clear()
+	cacheKeys.Clear()
+}
+
+// CacheInvalidatePrefix drops dir, everything cached below it and the listing
+// of its parent directory
+func CacheInvalidatePrefix(dir string) {
+	key := cacheKey(dir)
+	prefix := strings.TrimSuffix(key, string(os.PathSeparator)) + string(os.PathSeparator)
+
+	for _, k := range cacheKeys.Keys() {
+		if k == key || strings.HasPrefix(k, prefix) {
+			CacheDelete(k)
+		}
+	}
+	CacheDelete(filepath.Dir(key))
 }
 
 func errorPrinter(log string, object string) {
-	go invistiageError(object)
+	investigate(object)
 	if _, err := os.Stat("GMSFS.Debug"); err != nil {
 		if os.IsNotExist(err) {
 			return
@@ -107,51 +249,68 @@ func errorPrinter(log string, object string) {
 		}
 	}
 
-	AppendStringToFile("GMSFS."+time.Now().Format(timeFlat)+".log", log+" stacktrace: "+stack+"\r\n")
+	AppendStringToFile(TimestampedName("GMSFS", ".log"), log+" stacktrace: "+stack+"\r\n")
 }
 
 // This function catches errors from the filecache - some errors can be that a file
 // was tried to op read that does not exist, other can be cache objects not consisten
-// with file system and needs to be fixed.
-func invistiageError(name string) {
+// with file system and needs to be fixed. Runs on the workers of
+// SetInvestigateOptions, a repaired entry is reported to SubscribeErrors.
+func invistiageError(name string, quiet bool) {
 	if name == "" {
 		return
 	}
 	name = cleanPath(name)
-	fmt.Println("Invistiage object: " + name)
-	_, ok := CacheGet(strings.ToLower(cleanPath(name)))
+	if !quiet {
+		fmt.Println("Invistiage object: " + name)
+	}
+	info, ok := CacheGet(cacheKey(name))
 	if ok == true {
-		_, err := os.Stat(cleanPath(name))
+		_, err := os.Stat(longPath(cleanPath(name)))
 		if err != nil {
 			//We know the filesystem seems to have a issue with this object, so we clean it form the cache
-			CacheDelete(cleanPath(name))
-			UpdateDirectoryContents(filepath.Dir(strings.ToLower(cleanPath(name))))
+			CacheDelete(cacheKey(name))
+			UpdateDirectoryContents(filepath.Dir(name))
+			if info.Exists {
+				publishError(ErrorEvent{Op: "investigate", Path: name, Err: err, Cache: true, Repaired: true})
+			}
 		}
 	}
 }
 
-func cleanPath(path string) string {
-	path = filepath.Clean(path)
-	fs := strings.SplitN(path, ":", 2)
-	if len(fs) == 2 {
-		path = fs[1]
+func OpenFile(name string, flag int, perm os.FileMode) (_ *os.File, err error) {
+	defer startOp("OpenFile", name).end(&err)
+	if err := injectedFault("OpenFile", name); err != nil {
+		return nil, err
 	}
-
-	return path
-}
-
-func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
-	lowerCaseName := strings.ToLower(name)
-	file, err := os.OpenFile(name, flag, perm)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 && planned("open", name, "") {
+		return nil, ErrDryRun
+	}
+	lowerCaseName := cacheKey(name)
+	var before int64
+	if flag&os.O_TRUNC != 0 {
+		before = auditSize(name)
+	}
+	ioWait()
+	file, err := os.OpenFile(longPath(name), flag, perm)
+	ioDone()
 	if err != nil {
 		errorPrinter("OpenFile: "+err.Error(), name)
 		return nil, err
 	}
 
 	// Check if the file was newly created and update cache
+	var cacheErr error
 	if flag&os.O_CREATE != 0 {
-		UpdateFileInfo(name)
-		UpdateDirectoryContents(filepath.Dir(name))
+		cacheErr = firstError(UpdateFileInfo(name), UpdateDirectoryContents(filepath.Dir(name)))
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 {
+		publishInvalidation(name)
+		audit("open", name, "", -before)
+	}
+	if cacheErr != nil {
+		file.Close()
+		return nil, cacheErr
 	}
 
 	// Check if file info is already in the cache
@@ -178,7 +337,31 @@ func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
 	return file, nil
 }
 
+// Write realigns writes of DirectIO files and syncs after every write under
+// SyncEveryWrite
+func (cf *CachedFile) Write(b []byte) (int, error) {
+	var n int
+	var err error
+	if cf.direct {
+		n, err = cf.writeDirect(b)
+	} else {
+		n, err = cf.File.Write(b)
+	}
+	if err == nil && currentSyncPolicy() == SyncEveryWrite {
+		err = cf.File.Sync()
+	}
+	return n, err
+}
+
 func (cf *CachedFile) Close() error {
+	if syncOnClose() {
+		if err := cf.File.Sync(); err != nil {
+			errorPrinter("Close (Sync): "+err.Error(), cf.Name())
+			cf.File.Close()
+			return err
+		}
+	}
+
 	// Update file info in cache before closing
 	stat, err := cf.File.Stat()
 	if err != nil {
@@ -195,37 +378,63 @@ func (cf *CachedFile) Close() error {
 		Name:         filepath.Base(cf.path),
 	}
 
-	lowerCasePath := strings.ToLower(cf.path)
+	lowerCasePath := cacheKey(cf.path)
 	CacheAdd(lowerCasePath, fileInfo)
-	CacheDelete(strings.ToLower(filepath.Dir(cf.path)))
+	CacheDelete(cacheKey(filepath.Dir(cf.path)))
+	publishInvalidation(cf.path)
+	audit("write", cf.path, "", stat.Size())
 	// Now close the file
 	return cf.File.Close()
 }
 
-func Create(name string) (*CachedFile, error) {
+// Create creates or truncates name, opts select synchronous or direct I/O
+func Create(name string, opts ...OpenOption) (_ *CachedFile, err error) {
+	defer startOp("Create", name).end(&err)
+	if err := injectedFault("Create", name); err != nil {
+		return nil, err
+	}
+	if planned("create", name, "") {
+		return nil, ErrDryRun
+	}
 	name = cleanPath(name)
+	before := auditSize(name)
 
-	file, err := os.Create(name)
+	ioWait()
+	options := applyOpenOptions(opts)
+	file, err := openWithOptions(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666, options)
+	ioDone()
 	if err != nil {
 		errorPrinter("Create: "+err.Error(), name)
 		return nil, err
 	}
 
-	sname := strings.ToLower(name)
+	sname := cacheKey(name)
 	d, _ := filepath.Split(sname)
-	UpdateFileInfo(sname)
-	CacheDelete(strings.ToLower(d))
+	cacheErr := UpdateFileInfo(name)
+	CacheDelete(cacheKey(d))
+	publishInvalidation(name)
+	audit("create", name, "", -before)
+	if cacheErr != nil {
+		file.Close()
+		return nil, cacheErr
+	}
 
 	// Wrap the *os.File in CachedFile
-	return &CachedFile{File: file, path: name}, nil
+	return &CachedFile{File: file, path: name, direct: options.direct}, nil
 }
 
-func Open(name string) (*os.File, error) {
+// Open opens name for reading, opts select synchronous or direct I/O
+func Open(name string, opts ...OpenOption) (_ *os.File, err error) {
+	defer startOp("Open", name).end(&err)
+	if err := injectedFault("Open", name); err != nil {
+		return nil, err
+	}
 	name = cleanPath(name)
-	lowerCaseName := strings.ToLower(name)
+	lowerCaseName := cacheKey(name)
 
-	// Open the file using os.Open
-	file, err := os.Open(name)
+	ioWait()
+	file, err := openWithOptions(name, os.O_RDONLY, 0, applyOpenOptions(opts))
+	ioDone()
 	if err != nil {
 		errorPrinter("Open: "+err.Error(), name)
 		return nil, err
@@ -255,11 +464,21 @@ func Open(name string) (*os.File, error) {
 	return file, nil
 }
 
-func Delete(name string) error {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+func Delete(name string) (err error) {
+	defer startOp("Delete", name).end(&err)
+	if err := injectedFault("Delete", name); err != nil {
+		return err
+	}
+	if planned("delete", name, "") {
+		return nil
+	}
+	lowerCaseName := cacheKey(name)
+	before := auditSize(name)
 
 	// Remove the file from the filesystem
-	err := os.Remove(name) // Use original case for filesystem operations
+	ioWait()
+	err = os.Remove(longPath(name)) // Use original case for filesystem operations
+	ioDone()
 	if err != nil {
 		errorPrinter("Delete: "+err.Error(), name)
 		return err
@@ -269,22 +488,34 @@ func Delete(name string) error {
 	CacheDelete(lowerCaseName)
 	// Expire the directory contents in the cache
 	CacheDelete(filepath.Dir(lowerCaseName))
+	publishInvalidation(name)
+	audit("delete", name, "", -before)
 	return nil
 }
 
-func ReadFile(name string) ([]byte, error) {
+func ReadFile(name string) (_ []byte, err error) {
+	op := startOp("ReadFile", name)
+	defer op.end(&err)
+	if err := injectedFault("ReadFile", name); err != nil {
+		return nil, err
+	}
 	// Read the file contents
-	content, err := os.ReadFile(name) // Use the original case for filesystem operations
+	ioWait()
+	content, err := os.ReadFile(longPath(name)) // Use the original case for filesystem operations
+	ioDone()
 	if err != nil {
 		errorPrinter("ReadFile: "+err.Error(), name)
 		return nil, err
 	}
+	op.bytes(int64(len(content)))
 
 	return content, nil
 }
 
+// FileExists reports whether name exists, as a file or a directory; see
+// FileExistsStrict and DirExists to tell them apart
 func FileExists(name string) bool {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+	lowerCaseName := cacheKey(name)
 	if temp, ok := CacheGet(lowerCaseName); ok {
 		fileInfo := temp
 		return fileInfo.Exists
@@ -294,93 +525,163 @@ func FileExists(name string) bool {
 	if os.IsNotExist(err) {
 		return false
 	} else if err == nil {
-		UpdateFileInfo(lowerCaseName)
-		return true
+		return true // Stat cached it
 	}
 	return false
 }
 
-func Mkdir(name string, perm os.FileMode) error {
+func Mkdir(name string, perm os.FileMode) (err error) {
+	defer startOp("Mkdir", name).end(&err)
+	if err := injectedFault("Mkdir", name); err != nil {
+		return err
+	}
+	if planned("mkdir", name, "") {
+		return nil
+	}
 	name = cleanPath(name) // Preserve original name for file operation
-	err := os.Mkdir(name, perm)
+	ioWait()
+	err = os.Mkdir(longPath(name), perm)
+	ioDone()
 	if err != nil {
 		errorPrinter("Mkdir: "+err.Error(), name)
 		return err
 	}
 
-	UpdateFileInfo(name) // Use the original name
-	UpdateDirectoryContents(filepath.Dir(name))
-	return nil
+	cacheErr := firstError(UpdateFileInfo(name), UpdateDirectoryContents(filepath.Dir(name))) // Use the original name
+	publishInvalidation(name)
+	audit("mkdir", name, "", 0)
+	return cacheErr
 }
 
-func MkdirAll(path string, perm os.FileMode) error {
+func MkdirAll(path string, perm os.FileMode) (err error) {
+	defer startOp("MkdirAll", path).end(&err)
+	if err := injectedFault("MkdirAll", path); err != nil {
+		return err
+	}
+	if planned("mkdir", path, "") {
+		return nil
+	}
 	path = cleanPath(path) // Preserve original path for file operation
 
-	if FileExists(path) == true {
+	if info, err := Stat(path); err == nil && info.Exists {
+		if !info.IsDir {
+			// Like os.MkdirAll, a file in the way is an error
+			return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+		}
 		return nil
 	}
 
-	err := os.MkdirAll(path, perm)
+	ioWait()
+	err = os.MkdirAll(longPath(path), perm)
+	ioDone()
 	if err != nil {
 		return err
 	}
 
-	UpdateDirectoryContents(path)
-	UpdateDirectoryContents(filepath.Dir(path))
+	cacheErr := firstError(UpdateDirectoryContents(path), UpdateDirectoryContents(filepath.Dir(path)))
+	publishInvalidation(path)
+	audit("mkdir", path, "", 0)
 
-	return nil
+	return cacheErr
 }
 
-func Append(name string, content []byte) error {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+func Append(name string, content []byte) (err error) {
+	op := startOp("Append", name)
+	defer op.end(&err)
+	op.bytes(int64(len(content)))
+	if err := injectedFault("Append", name); err != nil {
+		return err
+	}
+	if planned("append", name, "") {
+		return nil
+	}
+	lowerCaseName := cacheKey(name)
 	var file *os.File
-	var err error
 
 	// If not, open the file and store the handle in the map
-	file, err = os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	ioWait()
+	file, err = os.OpenFile(longPath(name), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	ioDone()
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Write the content to the file
+	// Write the content to the file, holding the key until the cached size
+	// includes it, so concurrent appends cannot lose or double count
+	unlock := lockKey(lowerCaseName)
 	written, err := file.Write(content)
+	if err == nil && syncOnClose() {
+		err = file.Sync()
+	}
 	if err != nil {
+		unlock()
 		errorPrinter("Append: "+err.Error(), name)
 		return err
 	}
 
-	_, b := CacheGet(lowerCaseName)
-	if b == false {
-		UpdateFileInfo(name)
-		UpdateDirectoryContents(filepath.Dir(lowerCaseName))
+	var cacheErr error
+	cached := addCachedSize(lowerCaseName, int64(written))
+	if !cached {
+		// A fresh stat already includes this write
+		_, cacheErr = storeFileInfo(name)
 	}
-	UpdateFileInfoWithSize(lowerCaseName, int64(written))
-	return nil
+	unlock()
+	if cacheErr != nil {
+		cacheErr = cacheFailed("UpdateFileInfo", name, cacheErr)
+	}
+	if !cached {
+		cacheErr = firstError(cacheErr, UpdateDirectoryContents(filepath.Dir(cleanPath(name))))
+	}
+	publishInvalidation(name)
+	audit("append", name, "", int64(written))
+	return cacheErr
 }
 
 func AppendStringToFile(name string, content string) error {
-	return Append(name, []byte(content))
+	return AppendString(name, content)
 }
 
-func WriteFile(name string, content []byte, perm os.FileMode) error {
+func WriteFile(name string, content []byte, perm os.FileMode) (err error) {
+	op := startOp("WriteFile", name)
+	defer op.end(&err)
+	op.bytes(int64(len(content)))
+	if err := injectedFault("WriteFile", name); err != nil {
+		return err
+	}
+	if planned("write", name, "") {
+		return nil
+	}
 	name = cleanPath(name)
-	lowerCaseName := strings.ToLower(name)
+	lowerCaseName := cacheKey(name)
+	before := auditSize(name)
 
 	// Write the new content to the file
-	err := os.WriteFile(name, content, perm)
+	ioWait()
+	err = writeFile(longPath(name), content, perm)
+	ioDone()
 
 	CacheDelete(filepath.Dir(lowerCaseName))
 	CacheDelete(lowerCaseName)
 	if err != nil {
 		return err
 	}
+	if checksumOnWriteEnabled() {
+		ioWait()
+		stat, err := os.Stat(longPath(name))
+		ioDone()
+		if err == nil {
+			cacheChecksum(name, stat, sha256Hex(content))
+		}
+	}
+	publishInvalidation(name)
+	audit("write", name, "", int64(len(content))-before)
 
 	return nil
 }
 
 func FileSize(name string) (int64, error) {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+	lowerCaseName := cacheKey(name)
 
 	// Check if file information is available in the cache
 	if f, ok := CacheGet(lowerCaseName); ok {
@@ -390,7 +691,9 @@ func FileSize(name string) (int64, error) {
 	}
 
 	// If not in cache, get file size from the filesystem
-	stat, err := os.Stat(name) // Original name for filesystem operation
+	ioWait()
+	stat, err := os.Stat(longPath(name)) // Original name for filesystem operation
+	ioDone()
 	if err != nil {
 		errorPrinter("FileSize: "+err.Error(), name)
 		return 0, err // File does not exist or other error occurred
@@ -403,7 +706,7 @@ func FileSize(name string) (int64, error) {
 }
 
 func FileSizeZeroOnError(name string) int64 {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+	lowerCaseName := cacheKey(name)
 
 	// Check if file information is available in the cache
 	if f, ok := CacheGet(lowerCaseName); ok {
@@ -413,7 +716,9 @@ func FileSizeZeroOnError(name string) int64 {
 	}
 
 	// If not in cache, get file size from the filesystem
-	stat, err := os.Stat(name) // Original name for filesystem operation
+	ioWait()
+	stat, err := os.Stat(longPath(name)) // Original name for filesystem operation
+	ioDone()
 	if err != nil {
 		return 0 // Return 0 if file does not exist or other error occurred
 	}
@@ -424,41 +729,124 @@ func FileSizeZeroOnError(name string) int64 {
 	return stat.Size()
 }
 
-func Rename(oldName, newName string) error {
-	lowerOldName := strings.ToLower(cleanPath(oldName))
-	lowerNewName := strings.ToLower(cleanPath(newName))
-	fmt.Println(oldName, newName)
-
-	if lowerOldName == lowerNewName {
+func Rename(oldName, newName string) (err error) {
+	defer startOp("Rename", oldName).end(&err)
+	if err := injectedFault("Rename", oldName); err != nil {
+		return err
+	}
+	if err := injectedFault("Rename", newName); err != nil {
+		return err
+	}
+	if planned("rename", oldName, newName) {
+		return nil
+	}
+	if cacheKey(oldName) == cacheKey(newName) {
 		return nil
 	}
 
-	err := os.Rename(oldName, newName)
+	ioWait()
+	err = os.Rename(longPath(oldName), longPath(newName))
+	ioDone()
 	if err != nil {
 		errorPrinter("Rename: "+err.Error(), oldName)
 		errorPrinter("Rename: "+err.Error(), newName)
 		return err
 	}
 
-	CacheDelete(lowerOldName)
-	UpdateDirectoryContents(filepath.Dir(lowerOldName))
-	UpdateDirectoryContents(filepath.Dir(lowerNewName))
+	cacheErr := renamed(oldName, newName)
+	audit("rename", oldName, newName, 0)
+	return cacheErr
+}
 
+func Chmod(name string, mode os.FileMode) (err error) {
+	defer startOp("Chmod", name).end(&err)
+	if err := injectedFault("Chmod", name); err != nil {
+		return err
+	}
+	if planned("chmod", name, "") {
+		return nil
+	}
+	ioWait()
+	err = os.Chmod(longPath(name), mode)
+	ioDone()
+	if err != nil {
+		errorPrinter("Chmod: "+err.Error(), name)
+		return err
+	}
+
+	CacheDelete(cacheKey(name))
+	publishInvalidation(name)
+	auditEntry(AuditEntry{Op: "chmod", Path: name, Mode: mode.String()})
 	return nil
 }
 
-func CopyFile(src, dst string) (err error) {
+// renamed updates the cache after oldName was renamed to newName on disk. A
+// renamed directory takes everything cached below both names with it.
+func renamed(oldName, newName string) error {
+	ioWait()
+	stat, statErr := os.Lstat(longPath(newName))
+	ioDone()
+	tree := statErr == nil && stat.IsDir()
+
+	if tree {
+		CacheInvalidatePrefix(oldName)
+		CacheInvalidatePrefix(newName)
+	} else {
+		CacheDelete(cacheKey(oldName))
+		CacheDelete(cacheKey(newName))
+	}
+	err := firstError(UpdateDirectoryContents(filepath.Dir(cleanPath(oldName))), UpdateDirectoryContents(filepath.Dir(cleanPath(newName))))
+	if tree {
+		publishTreeInvalidation(oldName)
+		publishTreeInvalidation(newName)
+	} else {
+		publishInvalidation(oldName)
+		publishInvalidation(newName)
+	}
+	return err
+}
+
+// CopyFile copies src to dst with its mode, and with CopyPreserveMetadata
+// its times, owner and extended attributes
+func CopyFile(src, dst string, opts ...CopyOption) error {
+	_, err := CopyFileWithResult(src, dst, opts...)
+	return err
+}
+
+// CopyResult tells how CopyFileWithResult copied a file
+type CopyResult struct {
+	Bytes  int64
+	Cloned bool // Shares extents with the source (reflink), no data was copied
+}
+
+// CopyFileWithResult works like CopyFile and reports whether the copy was a
+// clone. On Linux it first tries a FICLONE reflink (btrfs, XFS, ...), then
+// copy_file_range, then a plain buffered copy.
+func CopyFileWithResult(src, dst string, opts ...CopyOption) (result CopyResult, err error) {
+	op := startOp("CopyFile", src)
+	defer op.end(&err)
+	if err = injectedFault("CopyFile", src); err != nil {
+		return result, err
+	}
+	if planned("copy", src, dst) {
+		return result, nil
+	}
 	src = cleanPath(src)
 	dst = cleanPath(dst)
 
-	in, err := os.Open(src)
+	ioWait()
+	in, err := os.Open(longPath(src))
+	ioDone()
 	if err != nil {
 		errorPrinter("CopyFile (os.Open): "+err.Error(), src)
 		return
 	}
 	defer in.Close()
 
-	out, err := os.Create(dst)
+	before := auditSize(dst)
+	ioWait()
+	out, err := os.Create(longPath(dst))
+	ioDone()
 	if err != nil {
 		errorPrinter("CopyFile (os.Create): "+err.Error(), dst)
 		return
@@ -469,55 +857,136 @@ func CopyFile(src, dst string) (err error) {
 		}
 	}()
 
-	_, err = io.Copy(out, in)
-	if err != nil {
-		errorPrinter("CopyFile (io.Copy): "+err.Error(), "")
-		return
+	// Before reading, which updates the access time
+	preserve := applyCopyOptions(opts).preserve
+	var pre os.FileInfo
+	if preserve {
+		if pre, err = in.Stat(); err != nil {
+			return
+		}
 	}
 
-	err = out.Sync()
+	// With checksums on write, reuse the source checksum or hash while copying
+	var sum string
+	var h hash.Hash
+	if checksumOnWriteEnabled() {
+		if info, ok := CacheGet(cacheKey(src)); ok {
+			sum = info.SHA256
+		}
+		if sum == "" {
+			h = sha256.New()
+		}
+	}
+
+	var written int64
+	if h == nil && cloneFile(out, in) {
+		result.Cloned = true
+		var stat os.FileInfo
+		if stat, err = in.Stat(); err == nil {
+			written = stat.Size()
+		}
+	} else if h == nil {
+		// io.CopyBuffer hands *os.File pairs to copy_file_range where possible
+		written, err = copyBuffered(out, in)
+	} else {
+		written, err = copyBuffered(out, io.TeeReader(in, h))
+		sum = hex.EncodeToString(h.Sum(nil))
+	}
 	if err != nil {
-		errorPrinter("CopyFile (out.Sync): "+err.Error(), "")
+		errorPrinter("CopyFile (copyBuffered): "+err.Error(), "")
 		return
 	}
+	result.Bytes = written
+
+	if syncCopies() {
+		err = out.Sync()
+		if err != nil {
+			errorPrinter("CopyFile (out.Sync): "+err.Error(), "")
+			return
+		}
+	}
 
-	si, err := os.Stat(src)
+	ioWait()
+	si, err := os.Stat(longPath(src))
+	ioDone()
 	if err != nil {
 		errorPrinter("CopyFile (os.Stat): "+err.Error(), "")
 		return
 	}
-	err = os.Chmod(dst, si.Mode())
+	ioWait()
+	err = os.Chmod(longPath(dst), si.Mode())
+	ioDone()
 	if err != nil {
 		errorPrinter("CopyFile (os.Chmod): "+err.Error(), "")
 		return
 	}
+	if preserve {
+		if err = preserveMetadata(src, dst, pre); err != nil {
+			errorPrinter("CopyFile (preserveMetadata): "+err.Error(), dst)
+			return
+		}
+	}
 
-	UpdateDirectoryContents(filepath.Dir(dst))
+	if sum != "" {
+		if stat, err := out.Stat(); err == nil {
+			cacheChecksum(dst, stat, sum)
+		}
+	}
+	err = UpdateDirectoryContents(filepath.Dir(dst))
+	publishInvalidation(dst)
+	audit("copy", src, dst, written-before)
+	op.bytes(written)
 
 	return
 }
 
-func Remove(name string) error {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+func Remove(name string) (err error) {
+	defer startOp("Remove", name).end(&err)
+	if err := injectedFault("Remove", name); err != nil {
+		return err
+	}
+	if planned("remove", name, "") {
+		return nil
+	}
+	lowerCaseName := cacheKey(name)
+	before := auditSize(name)
 
 	CacheDelete(lowerCaseName)
 
-	err := os.Remove(name)
+	ioWait()
+	err = os.Remove(longPath(name))
+	ioDone()
 	if err != nil {
 		errorPrinter("Remove: "+err.Error(), name)
 		return err
 	}
 
-	UpdateDirectoryContents(filepath.Dir(lowerCaseName))
+	cacheErr := UpdateDirectoryContents(filepath.Dir(cleanPath(name)))
+	publishInvalidation(name)
+	audit("remove", name, "", -before)
 
-	return nil
+	return cacheErr
 }
 
-func CopyDir(src string, dst string) error {
-	src = cleanPath(src)
-	dst = cleanPath(dst)
+// CopyDir copies the directory tree src to dst, which must not exist yet.
+// opts select which entries are copied, see CopyInclude, CopyExclude and
+// CopyFilter, and how symlinks are treated, see CopySymlinks.
+func CopyDir(src string, dst string, opts ...CopyOption) (err error) {
+	defer startOp("CopyDir", src).end(&err)
+	if err := injectedFault("CopyDir", src); err != nil {
+		return err
+	}
+	if planned("copydir", src, dst) {
+		return nil
+	}
+
+	options := applyCopyOptions(opts)
+	return copyDir(cleanPath(src), cleanPath(dst), "", &options)
+}
 
-	_, ok := CacheGet(strings.ToLower(src))
+// copyDir copies src to dst, rel is the path of src below the CopyDir root
+func copyDir(src string, dst string, rel string, options *copyOptions) (err error) {
+	_, ok := CacheGet(cacheKey(src))
 	if ok == false {
 		ListFS(strings.ToLower(src))
 	}
@@ -541,7 +1010,20 @@ func CopyDir(src string, dst string) error {
 		errorPrinter("CopyDir (os.MkdirAll): "+err.Error(), dst)
 		return err
 	}
-	UpdateFileInfo(dst)          // Update cache for the new directory
+	if err := UpdateFileInfo(dst); err != nil { // Update cache for the new directory
+		return err
+	}
+
+	if options.symlinks == SymlinkFollow {
+		// Directories being copied, a link back to one of them is a cycle
+		stat, err := os.Stat(longPath(src))
+		if err != nil {
+			return err
+		}
+		options.ancestors = append(options.ancestors, stat)
+		defer func() { options.ancestors = options.ancestors[:len(options.ancestors)-1] }()
+	}
+
 	entries, err := ReadDir(src) // ReadDir uses cache
 	if err != nil {
 		errorPrinter("CopyDir (ReadDir): "+err.Error(), src)
@@ -551,28 +1033,49 @@ func CopyDir(src string, dst string) error {
 	for _, entry := range entries {
 		srcPath := filepath.Join(src, entry.Name)
 		dstPath := filepath.Join(dst, entry.Name)
+		entryRel := filepath.Join(rel, entry.Name)
+		if !options.selected(entryRel, entry) {
+			continue
+		}
 
-		if entry.IsDir {
-			err = CopyDir(srcPath, dstPath)
+		if entry.Mode&os.ModeSymlink != 0 {
+			err = copySymlink(srcPath, dstPath, entryRel, options)
+			if err != nil {
+				errorPrinter("CopyDir (copySymlink): "+err.Error(), srcPath)
+				return err
+			}
+		} else if entry.IsDir {
+			err = copyDir(srcPath, dstPath, entryRel, options)
 			if err != nil {
 				errorPrinter("CopyDir (CopyDir-1): "+err.Error(), srcPath)
 				errorPrinter("CopyDir (CopyDir-2): "+err.Error(), dstPath)
 				return err
 			}
-			UpdateDirectoryContents(dstPath)
-		} else {
-			// Skip symlinks
-			if entry.Mode&os.ModeSymlink != 0 {
-				continue
+			if err := UpdateDirectoryContents(dstPath); err != nil {
+				return err
 			}
-
-			err = CopyFile(srcPath, dstPath)
+		} else {
+			// CopyFile refreshes the listing of dst
+			err = CopyFile(srcPath, dstPath, options.fileOptions()...)
 			if err != nil {
 				errorPrinter("CopyDir (CopyFile-1): "+err.Error(), srcPath)
 				errorPrinter("CopyDir (CopyFile-2): "+err.Error(), dstPath)
 				return err
 			}
-			UpdateDirectoryContents(dstPath)
+		}
+	}
+
+	if options.preserve {
+		// Last, copying the entries changed the directory's mtime
+		ioWait()
+		stat, err := os.Stat(longPath(src))
+		ioDone()
+		if err == nil {
+			err = preserveMetadata(src, dst, stat)
+		}
+		if err != nil {
+			errorPrinter("CopyDir (preserveMetadata): "+err.Error(), dst)
+			return err
 		}
 	}
 
@@ -580,25 +1083,40 @@ func CopyDir(src string, dst string) error {
 }
 
 func ReadDir(dirName string) ([]FileInfo, error) {
-	lowerCaseDirName := strings.ToLower(cleanPath(dirName))
+	return readDirContext(context.Background(), dirName)
+}
+
+func readDirContext(ctx context.Context, dirName string) (_ []FileInfo, err error) {
+	op := startOpContext(ctx, "ReadDir", dirName)
+	defer op.end(&err)
+	if err := injectedFault("ReadDir", dirName); err != nil {
+		return nil, err
+	}
+	lowerCaseDirName := cacheKey(dirName)
 
 	// Check if the directory's information is already cached
-	if fc, ok := CacheGet(lowerCaseDirName); ok {
+	fc, ok := CacheGet(lowerCaseDirName)
+	op.cacheHit(ok && fc.Contents != nil)
+	if ok && fc.Contents != nil {
 		return fc.Contents, nil
 	}
 
 	// Open the directory
-	f, err := os.Open(dirName)
+	ioWait()
+	f, err := os.Open(longPath(dirName))
+	ioDone()
 	if err != nil {
-		log.Printf("ReadDir (os.Open): %v", err)
+		logf("ReadDir (os.Open): %v", err)
 		return nil, err
 	}
 	defer f.Close()
 
 	// Read the directory entries
+	ioWait()
 	dirs, err := f.ReadDir(-1)
+	ioDone()
 	if err != nil {
-		log.Printf("ReadDir (f.ReadDir): %v", err)
+		logf("ReadDir (f.ReadDir): %v", err)
 		return nil, err
 	}
 
@@ -610,7 +1128,7 @@ func ReadDir(dirName string) ([]FileInfo, error) {
 	for _, entry := range dirs {
 		entryStat, err := entry.Info()
 		if err != nil {
-			log.Printf("ReadDir (entry.Info): %v", err)
+			logf("ReadDir (entry.Info): %v", err)
 			return nil, err
 		}
 
@@ -628,34 +1146,55 @@ func ReadDir(dirName string) ([]FileInfo, error) {
 
 	// Cache the directory's information
 	dirInfo := FileInfo{
-		Exists:   true,
-		IsDir:    true,
-		Contents: fileInfos,
-		Name:     filepath.Base(dirName),
+		Exists:    true,
+		IsDir:     true,
+		Contents:  fileInfos,
+		Name:      filepath.Base(dirName),
+		CacheTime: clockNow(),
+	}
+	// Without the mode CopyDir would recreate the directory with none
+	if dstat, err := f.Stat(); err == nil {
+		dirInfo.Mode = dstat.Mode()
+		dirInfo.LastModified = dstat.ModTime()
 	}
 	CacheAdd(lowerCaseDirName, dirInfo)
 
 	return fileInfos, nil
 }
 
-func RemoveAll(path string) error {
+func RemoveAll(path string) (err error) {
+	defer startOp("RemoveAll", path).end(&err)
+	if err := injectedFault("RemoveAll", path); err != nil {
+		return err
+	}
+	if planned("removeall", path, "") {
+		return nil
+	}
 	path = cleanPath(path)
-	oserr := os.RemoveAll(path)
+	before := auditSize(path)
+	ioWait()
+	oserr := os.RemoveAll(longPath(path))
+	ioDone()
 
-	err := updateCacheAfterRemoveAll(strings.ToLower(path))
-	if err != nil {
-		errorPrinter("Remove: "+err.Error(), path)
-		return err
+	cacheErr := removedAll(path)
+	publishTreeInvalidation(path)
+	if oserr == nil {
+		audit("removeall", path, "", -before)
 	}
 
-	UpdateDirectoryContents(filepath.Dir(path))
+	return firstError(oserr, cacheErr)
+}
 
-	return oserr
+// removedAll updates the cache after the tree at path was removed from disk.
+// The subtree is dropped by key prefix, it cannot be walked any more.
+func removedAll(path string) error {
+	CacheInvalidatePrefix(path)
+	return UpdateDirectoryContents(filepath.Dir(path))
 }
 
 func ListFS(path string) []string {
 	var sysSlices []string
-	lowerCasePath := strings.ToLower(cleanPath(path))
+	lowerCasePath := cacheKey(path)
 
 	// First, check if the path is a directory
 	fileInfo, err := Stat(path)
@@ -688,7 +1227,7 @@ func ListFS(path string) []string {
 }
 
 func RecurseFS(path string) (sysSlices []string) {
-	lowerCasePath := strings.ToLower(cleanPath(path))
+	lowerCasePath := cacheKey(path)
 
 	//	temp, ok := FileCache.Get(lowerCasePath)
 	var files []FileInfo
@@ -729,13 +1268,13 @@ func RecurseFS(path string) (sysSlices []string) {
 }
 
 func FileAgeInSec(filename string) (age time.Duration, err error) {
-	lowerCaseFilename := strings.ToLower(cleanPath(filename))
+	lowerCaseFilename := cacheKey(filename)
 
 	// Check if file information is available in the cache
 	fileInfo, ok := CacheGet(lowerCaseFilename)
 	if ok && !fileInfo.Exists {
-		// If the file is marked as non-existent and it's been more than 5 minutes, remove it from the cache
-		if time.Now().Sub(fileInfo.CacheTime) > MaxCacheTime {
+		// Drop a negative entry once it is older than NegativeCacheTime
+		if clockSince(fileInfo.CacheTime) > NegativeCacheTime {
 			CacheDelete(lowerCaseFilename)
 		}
 	}
@@ -758,12 +1297,12 @@ func FileAgeInSec(filename string) (age time.Duration, err error) {
 			LastModified: stat.LastModified,
 			IsDir:        stat.IsDir,
 			Name:         filename,
-			CacheTime:    time.Now(),
+			CacheTime:    clockNow(),
 		}
 		CacheAdd(lowerCaseFilename, fileInfo)
 	}
 
-	return time.Now().Sub(fileInfo.LastModified), nil
+	return clockSince(fileInfo.LastModified), nil
 }
 
 func CopyDirFilesGlob(src string, dst string, fileMatch string) (err error) {
@@ -804,9 +1343,9 @@ func CopyDirFilesGlob(src string, dst string, fileMatch string) (err error) {
 			errorPrinter("CopyDirFilesGlob (CopyFile-2): "+err.Error(), filepath.Join(dst, itemBaseName))
 			return
 		}
-		CacheDelete(strings.ToLower(filepath.Join(dst, itemBaseName)))
+		CacheDelete(cacheKey(filepath.Join(dst, itemBaseName)))
 	}
-	CacheDelete(strings.ToLower(dst))
+	CacheDelete(cacheKey(dst))
 
 	return nil
 }
@@ -830,6 +1369,11 @@ func FindFilesInDir(dir string, pattern string) ([]string, error) {
 }
 
 func Glob(pattern string) ([]string, error) {
+	if matches, ok := globCacheGet(pattern); ok {
+		return matches, nil
+	}
+	generation, cacheable := globGeneration(pattern)
+
 	errorZ := errors.New("")
 
 	// First, try to match the pattern with files in the cache
@@ -847,6 +1391,10 @@ func Glob(pattern string) ([]string, error) {
 		}
 	}
 
+	if errorZ == nil && cacheable {
+		globCacheSet(pattern, generation, cachedMatches)
+	}
+
 	return cachedMatches, errorZ
 }
 
@@ -857,17 +1405,17 @@ func CachedGlob(pattern string) ([]string, error) {
 	// Read the directory
 	files, err := ReadDir(".")
 	if err != nil {
-		log.Printf("CachedGlob: %v", err)
+		logf("CachedGlob: %v", err)
 		return nil, err
 	}
 
 	// Iterate through all items in the directory
 	for _, file := range files {
-		fileInfo, ok := CacheGet(strings.ToLower(file.Name))
+		fileInfo, ok := CacheGet(cacheKey(file.Name))
 		if ok {
 			matched, err := filepath.Match(lowerCasePattern, strings.ToLower(fileInfo.Name))
 			if err != nil {
-				log.Printf("CachedGlob: %v", err)
+				logf("CachedGlob: %v", err)
 				return nil, err
 			}
 			if matched {
@@ -880,21 +1428,35 @@ func CachedGlob(pattern string) ([]string, error) {
 }
 
 func Stat(name string) (FileInfo, error) {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+	return statContext(context.Background(), name)
+}
+
+func statContext(ctx context.Context, name string) (_ FileInfo, err error) {
+	op := startOpContext(ctx, "Stat", name)
+	defer op.end(&err)
+	if err := injectedFault("Stat", name); err != nil {
+		return FileInfo{}, err
+	}
+	lowerCaseName := cacheKey(name)
 
 	// Check if file information is available in the cache
 	if fileInfo, ok := CacheGet(lowerCaseName); ok {
-		if fileInfo.CacheTime.Sub(time.Now()).Seconds() > MaxCacheTime.Seconds() {
+		if clockSince(fileInfo.CacheTime) > cacheTTL(lowerCaseName)+MaxStaleTime {
 			CacheDelete(lowerCaseName)
 		} else if fileInfo.Name == "" {
 			CacheDelete(lowerCaseName)
-		} else {
+		} else if fileInfo.Mode&os.ModeSymlink == 0 {
+			// Not a listed symlink, Stat describes the target of those
+			op.cacheHit(true)
 			return fileInfo, nil
 		}
 	}
+	op.cacheHit(false)
 
 	// If not in cache, get file info from the filesystem
-	stat, err := os.Stat(name)
+	ioWait()
+	stat, err := os.Stat(longPath(name))
+	ioDone()
 	if err != nil {
 		return FileInfo{}, err
 	}
@@ -908,7 +1470,7 @@ func Stat(name string) (FileInfo, error) {
 		LastModified: stat.ModTime(),
 		IsDir:        stat.IsDir(),
 		Name:         dirNameOnly, // Store the original name
-		CacheTime:    time.Now(),
+		CacheTime:    clockNow(),
 	}
 
 	// Update the cache with this new information
@@ -920,50 +1482,70 @@ func Stat(name string) (FileInfo, error) {
 	return info, nil
 }
 
-func updateCacheAfterRemoveAll(path string) error {
-	lowerCasePath := strings.ToLower(cleanPath(path))
-
-	fso, err := ReadDir(path)
-	if err != nil {
-		return nil
-	}
-	for _, b := range fso {
-		if b.IsDir {
-			updateCacheAfterRemoveAll(path + "/" + b.Name)
-		} else {
-			CacheDelete(path + "/" + b.Name)
-		}
+// UpdateFileInfoWithSize grows the cached size of name by sizeIncrement, as
+// one atomic update, or reads the full info when name is not cached
+func UpdateFileInfoWithSize(name string, sizeIncrement int64) error {
+	lowerCaseName := cacheKey(name)
+	unlock := lockKey(lowerCaseName)
+	ok := addCachedSize(lowerCaseName, sizeIncrement)
+	unlock()
+	if !ok {
+		// If the file is not in cache, retrieve the full info
+		return UpdateFileInfo(name)
 	}
-	CacheDelete(lowerCasePath)
-
 	return nil
 }
 
-func UpdateFileInfoWithSize(name string, sizeIncrement int64) {
-	lowerCaseName := strings.ToLower(cleanPath(name))
-	if fileInfo, ok := CacheGet(lowerCaseName); ok {
-		updatedFileInfo := fileInfo
-		updatedFileInfo.Size += sizeIncrement
-		updatedFileInfo.LastModified = time.Now() // Update the last modified time
-		CacheAdd(lowerCaseName, updatedFileInfo)
-	} else {
-		// If the file is not in cache, retrieve the full info
-		UpdateFileInfo(name)
+// addCachedSize is UpdateFileInfoWithSize for a locked key, it reports false
+// when the key is not cached
+func addCachedSize(lowerCaseName string, sizeIncrement int64) bool {
+	fileInfo, ok := CacheGet(lowerCaseName)
+	if !ok {
+		return false
 	}
+	updatedFileInfo := fileInfo
+	updatedFileInfo.Size += sizeIncrement
+	updatedFileInfo.SHA256 = "" // A checksum cannot be extended
+	updatedFileInfo.ContentType = ""
+	updatedFileInfo.LastModified = clockNow() // Update the last modified time
+	cacheAddSync(lowerCaseName, updatedFileInfo)
+	return true
 }
 
-func UpdateFileInfo(name string) {
-	lowerCaseName := strings.ToLower(cleanPath(name))
+// UpdateFileInfo refreshes the cached info of name from disk, and the
+// listing when name is a directory. The error is only returned in strict
+// mode, see SetStrictMode.
+func UpdateFileInfo(name string) error {
+	lowerCaseName := cacheKey(name)
+	unlock := lockKey(lowerCaseName)
+	info, err := storeFileInfo(name)
+	unlock()
+	if err != nil {
+		return cacheFailed("UpdateFileInfo", name, err)
+	}
+
+	if info.IsDir {
+		return UpdateDirectoryContents(name)
+	}
+	return nil
+}
+
+// storeFileInfo is UpdateFileInfo for a locked key, without the directory
+// listing. On error the cached info is dropped, it cannot be trusted.
+func storeFileInfo(name string) (FileInfo, error) {
+	lowerCaseName := cacheKey(name)
 	var info FileInfo
 
 	// Check if the file exists
-	stat, err := os.Stat(name) // Use the original case for filesystem operations
+	ioWait()
+	stat, err := os.Stat(longPath(name)) // Use the original case for filesystem operations
+	ioDone()
 	if err != nil {
-		if os.IsNotExist(err) {
-			info = FileInfo{Exists: false, CacheTime: time.Now()}
-		} else {
-			return // Handle other potential errors
+		if !os.IsNotExist(err) {
+			CacheDelete(lowerCaseName)
+			return info, err
 		}
+		info = FileInfo{Exists: false, CacheTime: clockNow()}
 	} else {
 		info = FileInfo{
 			Exists:       true,
@@ -972,34 +1554,42 @@ func UpdateFileInfo(name string) {
 			LastModified: stat.ModTime(),
 			IsDir:        stat.IsDir(),
 			Name:         stat.Name(), // Preserve the original file name
-			CacheTime:    time.Now(),
+			CacheTime:    clockNow(),
 		}
 	}
 
 	// Update the FileCache
-	CacheAdd(lowerCaseName, info)
-
-	if info.IsDir {
-		UpdateDirectoryContents(name)
-	}
+	cacheAddSync(lowerCaseName, info)
+	return info, nil
 }
 
-func UpdateDirectoryContents(dirName string) {
+// UpdateDirectoryContents refreshes the cached listing of dirName from disk.
+// A directory that no longer exists just loses its listing. The error is
+// only returned in strict mode, see SetStrictMode.
+func UpdateDirectoryContents(dirName string) error {
 	dirName = cleanPath(dirName)
-	lowerCaseDirName := strings.ToLower(dirName)
+	lowerCaseDirName := cacheKey(dirName)
 
-	files, err := os.ReadDir(dirName) // Use the original case for filesystem operations
+	ioWait()
+	files, err := os.ReadDir(longPath(dirName)) // Use the original case for filesystem operations
+	ioDone()
 	if err != nil {
-		log.Printf("UpdateDirectoryContents (os.ReadDir): %v", err)
-		return // Handle error
+		CacheDelete(lowerCaseDirName)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return cacheFailed("UpdateDirectoryContents", dirName, err)
 	}
 
 	var contents []FileInfo
 	for _, file := range files {
 		fileInfo, err := file.Info()
 		if err != nil {
-			log.Printf("UpdateDirectoryContents (file.Info): %v", err)
-			continue
+			if os.IsNotExist(err) {
+				continue // Removed since the ReadDir
+			}
+			CacheDelete(lowerCaseDirName)
+			return cacheFailed("UpdateDirectoryContents", dirName, err)
 		}
 
 		info := FileInfo{
@@ -1009,16 +1599,22 @@ func UpdateDirectoryContents(dirName string) {
 			LastModified: fileInfo.ModTime(),
 			IsDir:        fileInfo.IsDir(),
 			Name:         fileInfo.Name(), // Preserve the original file name
-			CacheTime:    time.Now(),
+			CacheTime:    clockNow(),
 		}
 
 		contents = append(contents, info)
 	}
 
-	dstat, err := Stat(dirName)
+	// Not Stat, which lists new directories through this function again
+	ioWait()
+	dstat, err := os.Stat(longPath(dirName))
+	ioDone()
 	if err != nil {
-		log.Printf("UpdateDirectoryContents (Stat): %v", err)
-		return
+		CacheDelete(lowerCaseDirName)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return cacheFailed("UpdateDirectoryContents", dirName, err)
 	}
 
 	dirNameOnly := filepath.Base(dirName) // Get only the directory name
@@ -1027,10 +1623,11 @@ func UpdateDirectoryContents(dirName string) {
 		IsDir:        true,
 		Name:         dirNameOnly,
 		Contents:     contents,
-		LastModified: dstat.LastModified,
-		Mode:         dstat.Mode,
-		CacheTime:    time.Now(),
+		LastModified: dstat.ModTime(),
+		Mode:         dstat.Mode(),
+		CacheTime:    clockNow(),
 	}
 
 	CacheAdd(lowerCaseDirName, dirInfo)
+	return nil
 }
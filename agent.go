@@ -0,0 +1,327 @@
+package GMSFS
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewAgent exposes the cached operations for the tree below root over HTTP:
+//
+//	GET    /stat?path=p
+//	GET    /readdir?path=p
+//	GET    /file?path=p
+//	PUT    /file?path=p&perm=0644
+//	DELETE /file?path=p
+//	GET    /watch?path=p&interval=1s   (JSON lines, one WatchEvent per line)
+func NewAgent(root string) http.Handler {
+	a := &agent{root: cleanPath(root)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stat", a.stat)
+	mux.HandleFunc("/readdir", a.readDir)
+	mux.HandleFunc("/file", a.file)
+	mux.HandleFunc("/watch", a.watch)
+	return mux
+}
+
+type agent struct {
+	root string
+}
+
+type agentError struct {
+	Error    string `json:"error"`
+	NotExist bool   `json:"notExist"`
+}
+
+func (a *agent) path(r *http.Request) (string, error) {
+	return joinRoot(a.root, r.URL.Query().Get("path"))
+}
+
+func agentFail(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if os.IsNotExist(err) {
+		status = http.StatusNotFound
+	} else if os.IsPermission(err) {
+		status = http.StatusForbidden
+	} else if errors.Is(err, fs.ErrInvalid) {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(agentError{Error: err.Error(), NotExist: os.IsNotExist(err)})
+}
+
+func agentJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (a *agent) stat(w http.ResponseWriter, r *http.Request) {
+	name, err := a.path(r)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+	info, err := Stat(name)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+	info.Contents = nil
+
+	agentJSON(w, info)
+}
+
+func (a *agent) readDir(w http.ResponseWriter, r *http.Request) {
+	name, err := a.path(r)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+	contents, err := ReadDir(name)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+
+	agentJSON(w, contents)
+}
+
+func (a *agent) file(w http.ResponseWriter, r *http.Request) {
+	name, err := a.path(r)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		content, err := ReadFile(name)
+		if err != nil {
+			agentFail(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(content)
+	case http.MethodPut:
+		perm := os.FileMode(0644)
+		if p := r.URL.Query().Get("perm"); p != "" {
+			v, err := strconv.ParseUint(p, 8, 32)
+			if err != nil {
+				http.Error(w, "invalid perm", http.StatusBadRequest)
+				return
+			}
+			perm = os.FileMode(v)
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			agentFail(w, err)
+			return
+		}
+		if err := WriteFile(name, content, perm); err != nil {
+			agentFail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := Remove(name); err != nil {
+			agentFail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *agent) watch(w http.ResponseWriter, r *http.Request) {
+	interval := time.Second
+	if i := r.URL.Query().Get("interval"); i != "" {
+		d, err := time.ParseDuration(i)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid interval", http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+
+	name, err := a.path(r)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+	events, err := Watch(r.Context(), name, interval)
+	if err != nil {
+		agentFail(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	enc := json.NewEncoder(w)
+	for event := range events {
+		// Report paths relative to the agent root, like the requests use
+		event.Path = strings.TrimPrefix(strings.TrimPrefix(event.Path, a.root), string(os.PathSeparator))
+		if err := enc.Encode(event); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// AgentClient talks to an agent created with NewAgent. It implements Backend,
+// so wrapping it in a CachedBackend gives a cache-aware remote client.
+type AgentClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewAgentClient(baseURL string) *AgentClient {
+	return &AgentClient{BaseURL: strings.TrimSuffix(baseURL, "/"), Client: http.DefaultClient}
+}
+
+func (ac *AgentClient) do(ctx context.Context, method string, endpoint string, name string, query url.Values, body []byte) (*http.Response, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("path", name)
+
+	req, err := http.NewRequestWithContext(ctx, method, ac.BaseURL+endpoint+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	client := ac.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var ae agentError
+		json.NewDecoder(resp.Body).Decode(&ae)
+		switch {
+		case ae.NotExist || resp.StatusCode == http.StatusNotFound:
+			return nil, &fs.PathError{Op: strings.TrimPrefix(endpoint, "/"), Path: name, Err: fs.ErrNotExist}
+		case resp.StatusCode == http.StatusForbidden:
+			return nil, &fs.PathError{Op: strings.TrimPrefix(endpoint, "/"), Path: name, Err: fs.ErrPermission}
+		}
+		return nil, fmt.Errorf("agent %s %s: %s %s", method, name, resp.Status, ae.Error)
+	}
+
+	return resp, nil
+}
+
+func (ac *AgentClient) Stat(name string) (FileInfo, error) {
+	var info FileInfo
+	resp, err := ac.do(context.Background(), http.MethodGet, "/stat", name, nil, nil)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	return info, err
+}
+
+func (ac *AgentClient) ReadDir(name string) ([]FileInfo, error) {
+	var contents []FileInfo
+	resp, err := ac.do(context.Background(), http.MethodGet, "/readdir", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&contents)
+	return contents, err
+}
+
+func (ac *AgentClient) ReadFile(name string) ([]byte, error) {
+	resp, err := ac.do(context.Background(), http.MethodGet, "/file", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (ac *AgentClient) WriteFile(name string, content []byte, perm os.FileMode) error {
+	query := url.Values{}
+	query.Set("perm", strconv.FormatUint(uint64(perm.Perm()), 8))
+
+	resp, err := ac.do(context.Background(), http.MethodPut, "/file", name, query, content)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (ac *AgentClient) Remove(name string) error {
+	resp, err := ac.do(context.Background(), http.MethodDelete, "/file", name, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Watch streams the changes the agent sees in dir until ctx is done
+func (ac *AgentClient) Watch(ctx context.Context, dir string, interval time.Duration) (<-chan WatchEvent, error) {
+	query := url.Values{}
+	query.Set("interval", interval.String())
+
+	resp, err := ac.do(ctx, http.MethodGet, "/watch", dir, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var event WatchEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,94 @@
+package GMSFS
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppendBatch appends every chunk to name with one open and one cache update,
+// for callers that would otherwise call Append in a tight loop
+func AppendBatch(name string, chunks [][]byte) error {
+	return appendBatch(name, len(chunks), syncOnClose(), func(f *os.File, i int) (int, error) {
+		return f.Write(chunks[i])
+	})
+}
+
+// AppendStrings is AppendBatch for strings, written without converting them
+// to []byte first
+func AppendStrings(name string, strs []string) error {
+	return appendBatch(name, len(strs), syncOnClose(), func(f *os.File, i int) (int, error) {
+		return f.WriteString(strs[i])
+	})
+}
+
+// AppendString appends s to name with WriteString, so large strings are not
+// copied into a []byte first
+func AppendString(name string, s string) error {
+	return appendBatch(name, 1, syncOnClose(), func(f *os.File, i int) (int, error) {
+		return f.WriteString(s)
+	})
+}
+
+// Appendf formats like fmt.Printf straight into name
+func Appendf(name string, format string, args ...interface{}) error {
+	return appendBatch(name, 1, syncOnClose(), func(f *os.File, i int) (int, error) {
+		return fmt.Fprintf(f, format, args...)
+	})
+}
+
+func appendBatch(name string, count int, sync bool, write func(f *os.File, i int) (int, error)) (err error) {
+	op := startOp("AppendBatch", name)
+	defer op.end(&err)
+	if err := injectedFault("AppendBatch", name); err != nil {
+		return err
+	}
+	if planned("append", name, "") {
+		return nil
+	}
+	name = cleanPath(name)
+	lowerCaseName := cacheKey(name)
+
+	ioWait()
+	file, err := os.OpenFile(longPath(name), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	ioDone()
+	if err != nil {
+		errorPrinter("AppendBatch (os.OpenFile): "+err.Error(), name)
+		return err
+	}
+	defer file.Close()
+
+	// Held until the cached size includes the batch, see Append
+	unlock := lockKey(lowerCaseName)
+	var written int64
+	for i := 0; i < count && err == nil; i++ {
+		var n int
+		n, err = write(file, i)
+		written += int64(n)
+	}
+	if err == nil && sync {
+		err = file.Sync()
+	}
+	op.bytes(written)
+
+	var cacheErr error
+	cached := addCachedSize(lowerCaseName, written)
+	if !cached {
+		_, cacheErr = storeFileInfo(name)
+	}
+	unlock()
+	if cacheErr != nil {
+		cacheErr = cacheFailed("UpdateFileInfo", name, cacheErr)
+	}
+	if !cached {
+		cacheErr = firstError(cacheErr, UpdateDirectoryContents(filepath.Dir(name)))
+	}
+	publishInvalidation(name)
+	audit("append", name, "", written)
+
+	if err != nil {
+		errorPrinter("AppendBatch: "+err.Error(), name)
+		return err
+	}
+	return cacheErr
+}
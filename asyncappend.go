@@ -0,0 +1,142 @@
+package GMSFS
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAppenderClosed is returned by AsyncAppender.Append after Close
+var ErrAppenderClosed = errors.New("async appender is closed")
+
+// AsyncAppender coalesces appends (group commit). Appends to one path within
+// Window are collected by a goroutine for that path and written with a single
+// write and fsync. Append returns before the data is on disk; write errors go
+// to OnError and to the next Flush or Close.
+type AsyncAppender struct {
+	Window  time.Duration
+	OnError func(name string, err error)
+
+	mutex  sync.Mutex
+	files  map[string]*appendQueue
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type appendQueue struct {
+	name    string
+	pending [][]byte
+	waiters []chan error
+	wake    chan struct{}
+}
+
+// NewAsyncAppender returns an appender that writes every window, onError may
+// be nil
+func NewAsyncAppender(window time.Duration, onError func(name string, err error)) *AsyncAppender {
+	return &AsyncAppender{Window: window, OnError: onError, files: map[string]*appendQueue{}}
+}
+
+// Append queues content for name. content is copied, the caller may reuse it.
+func (a *AsyncAppender) Append(name string, content []byte) error {
+	name = cleanPath(name)
+	key := cacheKey(name)
+	data := append([]byte(nil), content...)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		return ErrAppenderClosed
+	}
+
+	q := a.files[key]
+	if q == nil {
+		q = &appendQueue{name: name, wake: make(chan struct{}, 1)}
+		a.files[key] = q
+		a.wg.Add(1)
+		go a.run(key, q)
+	}
+	q.pending = append(q.pending, data)
+	return nil
+}
+
+// Flush writes everything queued so far and returns the first error
+func (a *AsyncAppender) Flush() error {
+	a.mutex.Lock()
+	var waiters []chan error
+	for _, q := range a.files {
+		done := make(chan error, 1)
+		q.waiters = append(q.waiters, done)
+		waiters = append(waiters, done)
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	a.mutex.Unlock()
+
+	var first error
+	for _, done := range waiters {
+		if err := <-done; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close flushes the queued appends and stops the goroutines. Later Appends
+// return ErrAppenderClosed.
+func (a *AsyncAppender) Close() error {
+	a.mutex.Lock()
+	a.closed = true
+	a.mutex.Unlock()
+
+	err := a.Flush()
+	a.wg.Wait()
+	return err
+}
+
+func (a *AsyncAppender) run(key string, q *appendQueue) {
+	defer a.wg.Done()
+
+	timer := time.NewTimer(a.Window)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-q.wake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		a.mutex.Lock()
+		pending, waiters := q.pending, q.waiters
+		q.pending, q.waiters = nil, nil
+		if len(pending) == 0 {
+			// Idle for a whole window, the next Append starts a new goroutine
+			delete(a.files, key)
+			a.mutex.Unlock()
+			for _, done := range waiters {
+				done <- nil
+			}
+			return
+		}
+		a.mutex.Unlock()
+
+		data := bytes.Join(pending, nil)
+		err := appendBatch(q.name, 1, true, func(f *os.File, i int) (int, error) {
+			return f.Write(data)
+		})
+		if err != nil && a.OnError != nil {
+			a.OnError(q.name, err)
+		}
+		for _, done := range waiters {
+			done <- err
+		}
+
+		timer.Reset(a.Window)
+	}
+}
@@ -0,0 +1,156 @@
+package GMSFS
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry records one mutating operation
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	Path      string    `json:"path"`
+	NewPath   string    `json:"new_path,omitempty"` // Destination of renames and copies
+	SizeDelta int64     `json:"size_delta"`
+	Mode      string    `json:"mode,omitempty"` // New mode of chmod
+	Caller    string    `json:"caller"`         // First function outside GMSFS, with file:line
+	User      string    `json:"user"`
+	PID       int       `json:"pid"`
+}
+
+// AuditSink receives the audit entries, see NewAuditLog for a JSON lines sink
+type AuditSink interface {
+	WriteAudit(entry AuditEntry) error
+}
+
+var (
+	auditMutex    sync.RWMutex
+	auditSink     AuditSink
+	auditUser     string
+	auditUserOnce sync.Once
+)
+
+// SetAuditSink records every Create, Write, Append, Delete, Rename, Chmod
+// (and the other mutating calls) to sink. nil turns auditing off again.
+func SetAuditSink(sink AuditSink) {
+	auditMutex.Lock()
+	auditSink = sink
+	auditMutex.Unlock()
+}
+
+type auditLog struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewAuditLog returns a sink writing one JSON object per line to w
+func NewAuditLog(w io.Writer) AuditSink {
+	return &auditLog{w: w}
+}
+
+// OpenAuditLog opens (or creates) an append-only JSON lines audit file
+func OpenAuditLog(name string) (AuditSink, *os.File, error) {
+	f, err := os.OpenFile(longPath(name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewAuditLog(f), f, nil
+}
+
+func (al *auditLog) WriteAudit(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	_, err = al.w.Write(append(line, '\n'))
+	return err
+}
+
+func auditing() bool {
+	auditMutex.RLock()
+	defer auditMutex.RUnlock()
+	return auditSink != nil || changeFeedActive()
+}
+
+// auditSize is the size of name before it is changed, only stat'ed while
+// auditing. Directories and missing files count as 0.
+func auditSize(name string) int64 {
+	if !auditing() {
+		return 0
+	}
+	stat, err := os.Lstat(longPath(name))
+	if err != nil || stat.IsDir() {
+		return 0
+	}
+	return stat.Size()
+}
+
+func audit(op string, name string, newName string, delta int64) {
+	auditEntry(AuditEntry{Op: op, Path: name, NewPath: newName, SizeDelta: delta})
+}
+
+func auditEntry(entry AuditEntry) {
+	auditMutex.RLock()
+	sink := auditSink
+	auditMutex.RUnlock()
+	feed := changeFeedActive()
+	if sink == nil && !feed {
+		return
+	}
+
+	entry.Time = time.Now()
+	entry.Path = cleanPath(entry.Path)
+	if entry.NewPath != "" {
+		entry.NewPath = cleanPath(entry.NewPath)
+	}
+	if feed {
+		publishChange(entry)
+	}
+	if sink == nil {
+		return
+	}
+
+	entry.Caller = auditCaller()
+	auditUserOnce.Do(func() { auditUser = currentUser() })
+	entry.User = auditUser
+	entry.PID = os.Getpid()
+
+	if err := sink.WriteAudit(entry); err != nil {
+		errorPrinter("audit (WriteAudit): "+err.Error(), entry.Path)
+	}
+}
+
+// auditCaller returns the first stack frame outside this package
+func auditCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	self := "github.com/inpadi/GMSFS."
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, self) {
+			return frame.Function + " " + frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}
@@ -0,0 +1,289 @@
+package GMSFS
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+// Backend is a storage system that can be fronted by the GMSFS metadata cache.
+// Names are slash separated and relative to the root of the backend.
+type Backend interface {
+	Stat(name string) (FileInfo, error)
+	ReadDir(name string) ([]FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, content []byte, perm os.FileMode) error
+	Remove(name string) error
+}
+
+// LocalBackend exposes a directory on the local filesystem through the
+// package level (already cached) functions.
+type LocalBackend struct {
+	Root string
+}
+
+func (lb *LocalBackend) path(name string) (string, error) {
+	return joinRoot(lb.Root, name)
+}
+
+// joinRoot maps a slash separated name onto root without allowing it to
+// escape root through ".." elements. Like http.Dir, names holding the OS
+// separator when it is not a slash are rejected: path.Clean keeps "..\.."
+// as one element, filepath.Join would walk up through it.
+func joinRoot(root string, name string) (string, error) {
+	if filepath.Separator != '/' && strings.ContainsRune(name, filepath.Separator) {
+		return "", &fs.PathError{Op: "join", Path: name, Err: fs.ErrInvalid}
+	}
+	return cleanJoin(root, name), nil
+}
+
+// cleanJoin is joinRoot for names known to be slash separated only
+func cleanJoin(root string, name string) string {
+	return filepath.Join(root, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (lb *LocalBackend) Stat(name string) (FileInfo, error) {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return Stat(fullName)
+}
+
+func (lb *LocalBackend) ReadDir(name string) ([]FileInfo, error) {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadDir(fullName)
+}
+
+func (lb *LocalBackend) ReadFile(name string) ([]byte, error) {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadFile(fullName)
+}
+
+func (lb *LocalBackend) WriteFile(name string, content []byte, perm os.FileMode) error {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return err
+	}
+	return WriteFile(fullName, content, perm)
+}
+
+func (lb *LocalBackend) Remove(name string) error {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return err
+	}
+	return Remove(fullName)
+}
+
+// Join returns the path of the slash separated name built from parts below
+// Root, for the package level functions. ".." elements cannot leave Root,
+// on Windows \ separates elements as well.
+func (lb *LocalBackend) Join(parts ...string) string {
+	return cleanJoin(lb.Root, filepath.ToSlash(path.Join(parts...)))
+}
+
+// Within reports whether name, a path on the local filesystem, lies inside
+// Root (or is Root). The check is lexical: a symlink inside Root pointing out
+// of it is not detected, Canonicalize name first for that.
+func (lb *LocalBackend) Within(name string) bool {
+	_, err := lb.Rel(name)
+	return err == nil
+}
+
+// Rel returns the slash separated name of the local path name relative to
+// Root, the form the Backend methods take. It fails when name is outside
+// Root.
+func (lb *LocalBackend) Rel(name string) (string, error) {
+	root := absPath(cleanPath(lb.Root))
+	rel, err := filepath.Rel(root, absPath(cleanPath(name)))
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", name, lb.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// prefixDirs is implemented by backends whose directories are only the common
+// prefixes of the names below them, like S3Backend, so there is nothing to
+// Stat. Backends wrapping another one forward it.
+type prefixDirs interface {
+	PrefixDirs() bool
+}
+
+func hasPrefixDirs(backend Backend) bool {
+	p, ok := backend.(prefixDirs)
+	return ok && p.PrefixDirs()
+}
+
+// CachedBackend fronts a remote Backend with the metadata cache. The prefix
+// (for example "s3://bucket") keeps its cache keys apart from local paths.
+type CachedBackend struct {
+	Backend Backend
+	prefix  string
+}
+
+// cachedBackends holds the CachedBackends by prefix, so revalidation can tell
+// their keys apart from local paths
+var cachedBackends = cmap.New[*CachedBackend]()
+
+func NewCachedBackend(prefix string, backend Backend) *CachedBackend {
+	cb := &CachedBackend{Backend: backend, prefix: strings.TrimSuffix(prefix, "/")}
+	if cb.prefix != "" {
+		cachedBackends.Set(cb.prefix, cb)
+	}
+	return cb
+}
+
+// backendOf returns the CachedBackend the cache key belongs to and the name
+// of the entry in it, false for the keys of local paths
+func backendOf(key string) (*CachedBackend, string, bool) {
+	for item := range cachedBackends.IterBuffered() {
+		if key == item.Key || strings.HasPrefix(key, item.Key+"/") {
+			return item.Val, strings.TrimPrefix(key, item.Key), true
+		}
+	}
+	return nil, "", false
+}
+
+// key is case sensitive and not normalized, unlike the keys of local paths:
+// object stores and most remote filesystems tell "A" and "a" apart
+func (cb *CachedBackend) key(name string) string {
+	return cb.prefix + path.Clean("/"+name)
+}
+
+// PrefixDirs forwards the prefixDirs interface of Backend
+func (cb *CachedBackend) PrefixDirs() bool { return hasPrefixDirs(cb.Backend) }
+
+func (cb *CachedBackend) Stat(name string) (FileInfo, error) {
+	key := cb.key(name)
+	if fileInfo, ok := CacheGet(key); ok && fileInfo.Exists {
+		return fileInfo, nil
+	}
+
+	return cb.stat(name)
+}
+
+// stat asks the backend and caches the answer
+func (cb *CachedBackend) stat(name string) (FileInfo, error) {
+	start := time.Now()
+	info, err := cb.Backend.Stat(name)
+	recordLatency("Stat", cb.prefix, time.Since(start))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info.CacheTime = clockNow()
+	CacheAdd(cb.key(name), info)
+
+	return info, nil
+}
+
+func (cb *CachedBackend) ReadDir(name string) ([]FileInfo, error) {
+	if dirInfo, ok := CacheGet(cb.key(name)); ok && dirInfo.IsDir && dirInfo.Contents != nil {
+		return dirInfo.Contents, nil
+	}
+
+	return cb.list(name)
+}
+
+// list asks the backend for the listing of name and caches it with the
+// entries
+func (cb *CachedBackend) list(name string) ([]FileInfo, error) {
+	key := cb.key(name)
+	start := time.Now()
+	contents, err := cb.Backend.ReadDir(name)
+	recordLatency("ReadDir", cb.prefix, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		contents = []FileInfo{}
+	}
+
+	now := clockNow()
+	for i := range contents {
+		contents[i].CacheTime = now
+		CacheAdd(cb.key(path.Join(name, contents[i].Name)), contents[i])
+	}
+
+	dirInfo := FileInfo{
+		Exists:    true,
+		IsDir:     true,
+		Mode:      os.ModeDir | 0755,
+		Name:      path.Base(path.Clean("/" + name)),
+		Contents:  contents,
+		CacheTime: now,
+	}
+	// A prefix has no metadata of its own, its Stat would only cost another
+	// round trip
+	if !hasPrefixDirs(cb.Backend) {
+		if stat, err := cb.Backend.Stat(name); err == nil {
+			dirInfo.Mode = stat.Mode
+			dirInfo.LastModified = stat.LastModified
+		}
+	}
+	// The listing is kept with the directory: CacheAdd would store the
+	// entries under folded child keys, where "A" and "a" collide
+	cacheSet(key, CacheItem{Value: dirInfo, Timestamp: now})
+	// Sets are buffered by ristretto, make the entries visible to Stat
+	cache.Wait()
+
+	return contents, nil
+}
+
+// refresh fetches the entry of name again, past the stale one in the cache,
+// for revalidateInBackground
+func (cb *CachedBackend) refresh(name string, dir bool) {
+	var err error
+	if dir {
+		_, err = cb.list(name)
+	} else {
+		_, err = cb.stat(name)
+	}
+	if err != nil {
+		CacheDelete(cb.key(name))
+	}
+}
+
+func (cb *CachedBackend) ReadFile(name string) ([]byte, error) {
+	start := time.Now()
+	content, err := cb.Backend.ReadFile(name)
+	recordLatency("ReadFile", cb.prefix, time.Since(start))
+	return content, err
+}
+
+func (cb *CachedBackend) WriteFile(name string, content []byte, perm os.FileMode) error {
+	start := time.Now()
+	err := cb.Backend.WriteFile(name, content, perm)
+	recordLatency("WriteFile", cb.prefix, time.Since(start))
+
+	CacheDelete(cb.key(name))
+	CacheDelete(cb.key(path.Dir(path.Clean("/" + name))))
+
+	return err
+}
+
+func (cb *CachedBackend) Remove(name string) error {
+	start := time.Now()
+	err := cb.Backend.Remove(name)
+	recordLatency("Remove", cb.prefix, time.Since(start))
+
+	CacheDelete(cb.key(name))
+	CacheDelete(cb.key(path.Dir(path.Clean("/" + name))))
+
+	return err
+}
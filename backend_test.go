@@ -0,0 +1,75 @@
+package GMSFS_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// countingBackend counts the Stat calls that reach the backend
+type countingBackend struct {
+	GMSFS.Backend
+	stats int32
+}
+
+func (cb *countingBackend) Stat(name string) (GMSFS.FileInfo, error) {
+	atomic.AddInt32(&cb.stats, 1)
+	return cb.Backend.Stat(name)
+}
+
+func TestCachedBackendSoftCacheTime(t *testing.T) {
+	clock := GMSFS.NewManualClock(time.Now())
+	GMSFS.SetClock(clock)
+	defer GMSFS.SetClock(nil)
+	defer func(soft time.Duration) { GMSFS.SoftCacheTime = soft }(GMSFS.SoftCacheTime)
+	GMSFS.SoftCacheTime = time.Second
+	GMSFS.CacheFlush() // Nothing left from an earlier run of the test
+
+	local := &GMSFS.LocalBackend{Root: t.TempDir()}
+	if err := local.WriteFile("file", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingBackend{Backend: local}
+	backend := GMSFS.NewCachedBackend("mem://soft", counting)
+
+	if _, err := backend.Stat("file"); err != nil {
+		t.Fatal(err)
+	}
+	GMSFS.CacheWait()
+
+	// Past SoftCacheTime the entry has no local file to be checked against
+	clock.Advance(2 * time.Second)
+	if _, err := backend.Stat("file"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&counting.stats); n != 1 {
+		t.Errorf("backend Stat called %d times, want 1", n)
+	}
+}
+
+// prefixBackend has directories without metadata, like S3Backend
+type prefixBackend struct {
+	*countingBackend
+}
+
+func (pb prefixBackend) PrefixDirs() bool { return true }
+
+func TestCachedBackendSkipsPrefixDirStat(t *testing.T) {
+	local := &GMSFS.LocalBackend{Root: t.TempDir()}
+	if err := local.WriteFile("file", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingBackend{Backend: local}
+	// Wrapped, the limiter has to forward PrefixDirs
+	limited := GMSFS.NewLimitedBackend(prefixBackend{counting}, GMSFS.NewLimiter(1, 0))
+	backend := GMSFS.NewCachedBackend("mem://prefix", limited)
+
+	if _, err := backend.ReadDir(""); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&counting.stats); n != 0 {
+		t.Errorf("ReadDir of a prefix directory called Stat %d times, want none", n)
+	}
+}
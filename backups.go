@@ -0,0 +1,66 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RotateBackups keeps numbered copies of path before it is overwritten: it
+// removes path.keep, shifts path.N to path.N+1 and copies path to path.1, so
+// path.1 is always the newest backup. path itself stays in place. Nothing
+// happens when path does not exist.
+func RotateBackups(path string, keep int) (err error) {
+	defer startOp("RotateBackups", path).end(&err)
+	if err := injectedFault("RotateBackups", path); err != nil {
+		return err
+	}
+	path = cleanPath(path)
+	if keep < 1 || !FileExists(path) {
+		return nil
+	}
+
+	backup := func(n int) string { return path + "." + strconv.Itoa(n) }
+	if FileExists(backup(keep)) {
+		if err := Remove(backup(keep)); err != nil {
+			return err
+		}
+	}
+	for n := keep - 1; n >= 1; n-- {
+		if !FileExists(backup(n)) {
+			continue
+		}
+		if err := Rename(backup(n), backup(n+1)); err != nil {
+			return err
+		}
+	}
+	return CopyFile(path, backup(1))
+}
+
+// RotateTimestampedBackups is RotateBackups with timestamped copies: path is
+// copied to TimestampedName of its name and extension (config.json becomes
+// config.20240131_1542.json) and all but the keep newest such copies are
+// removed. Backups taken within the same minute replace each other.
+func RotateTimestampedBackups(path string, keep int) (err error) {
+	defer startOp("RotateTimestampedBackups", path).end(&err)
+	if err := injectedFault("RotateTimestampedBackups", path); err != nil {
+		return err
+	}
+	path = cleanPath(path)
+	if keep < 1 || !FileExists(path) {
+		return nil
+	}
+
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext)
+	if err := CopyFile(path, TimestampedName(prefix, ext)); err != nil {
+		return err
+	}
+	_, err = PruneTimestamped(filepath.Dir(path), filepath.Base(prefix), ext, keep)
+	if os.IsNotExist(err) {
+		// Removed concurrently
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// BatchWorkers bounds the number of concurrent stats in StatMany and ExistsMany
+var BatchWorkers = 16
+
+// StatMany stats every path, answering from the cache where possible and
+// stating the rest concurrently. Paths that do not exist or fail to stat are
+// left out of the result.
+func StatMany(paths []string) map[string]FileInfo {
+	result := make(map[string]FileInfo, len(paths))
+	var pending []string
+
+	for _, name := range paths {
+		info, ok := CacheGet(cacheKey(name))
+		switch {
+		case ok && !info.Exists:
+			continue // Cached as missing
+		case ok && info.Name != "" && info.Mode&os.ModeSymlink == 0:
+			// Listed symlinks are left to Stat, which follows them
+			result[name] = info
+			continue
+		}
+		pending = append(pending, name)
+	}
+
+	var mutex sync.Mutex
+	forEachConcurrent(pending, func(name string) {
+		info, err := Stat(name)
+		if err != nil {
+			return
+		}
+		mutex.Lock()
+		result[name] = info
+		mutex.Unlock()
+	})
+
+	return result
+}
+
+// ExistsMany reports for every path whether it exists, with the same cached
+// and concurrent lookups as StatMany
+func ExistsMany(paths []string) map[string]bool {
+	infos := StatMany(paths)
+
+	result := make(map[string]bool, len(paths))
+	for _, name := range paths {
+		_, result[name] = infos[name]
+	}
+	return result
+}
+
+// ExistsManyFromParents answers ExistsMany from the (cached) listings of the
+// parent directories only, reading each parent once and never stating a file
+func ExistsManyFromParents(paths []string) map[string]bool {
+	var dirs []string
+	listings := map[string][]FileInfo{}
+	for _, name := range paths {
+		dir := filepath.Dir(cleanPath(name))
+		if _, ok := listings[dir]; !ok {
+			listings[dir] = nil
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var mutex sync.Mutex
+	forEachConcurrent(dirs, func(dir string) {
+		contents, err := ReadDir(dir)
+		if err != nil {
+			return
+		}
+		mutex.Lock()
+		listings[dir] = contents
+		mutex.Unlock()
+	})
+
+	result := make(map[string]bool, len(paths))
+	for _, name := range paths {
+		cleaned := cleanPath(name)
+		dir := filepath.Dir(cleaned)
+		if dir == cleaned {
+			result[name] = FileExists(name) // A root has no parent listing
+			continue
+		}
+		_, result[name] = matchCase(listings[dir], filepath.Base(cleaned))
+	}
+	return result
+}
+
+// forEachConcurrent calls fn for every path using up to BatchWorkers goroutines
+func forEachConcurrent(paths []string, fn func(name string)) {
+	workers := BatchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				fn(name)
+			}
+		}()
+	}
+
+	for _, name := range paths {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+}
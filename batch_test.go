@@ -0,0 +1,38 @@
+package GMSFS_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inpadi/GMSFS"
+)
+
+func TestStatManyFollowsListedSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"target": "content"})
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("target", link); err != nil {
+		t.Skip(err)
+	}
+	// The listing caches the link with its lstat mode
+	if _, err := GMSFS.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	GMSFS.CacheWait()
+
+	got, ok := GMSFS.StatMany([]string{link})[link]
+	if !ok {
+		t.Fatalf("StatMany left out %s", link)
+	}
+	want, err := GMSFS.Stat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Mode != want.Mode || got.Size != want.Size {
+		t.Errorf("StatMany = mode %v size %d, Stat = mode %v size %d", got.Mode, got.Size, want.Mode, want.Size)
+	}
+	if got.Mode&os.ModeSymlink != 0 {
+		t.Errorf("StatMany returned the link itself, mode %v", got.Mode)
+	}
+}
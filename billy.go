@@ -0,0 +1,257 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// BillyFS implements billy.Filesystem on top of the cached functions, so
+// go-git can work on a repository through GMSFS. The directory walks of
+// status and checkout are answered from the cached listings, and the changes
+// they make keep the cache coherent.
+type BillyFS struct {
+	root string
+}
+
+func NewBillyFS(root string) *BillyFS {
+	return &BillyFS{root: cleanPath(root)}
+}
+
+func (b *BillyFS) path(name string) string {
+	// Billy names use the OS separator, as slashes their ".." are cleaned
+	return cleanJoin(b.root, filepath.ToSlash(name))
+}
+
+func (b *BillyFS) Create(filename string) (billy.File, error) {
+	return b.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (b *BillyFS) Open(filename string) (billy.File, error) {
+	return b.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (b *BillyFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	fullName := b.path(filename)
+	if flag&os.O_CREATE != 0 {
+		// Like osfs, creating a file creates its directory
+		if err := MkdirAll(filepath.Dir(fullName), 0755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := OpenFile(fullName, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &billyFile{File: file, name: filename, path: fullName, written: flag&(os.O_WRONLY|os.O_RDWR) != 0}, nil
+}
+
+func (b *BillyFS) Stat(filename string) (os.FileInfo, error) {
+	info, err := Stat(b.path(filename))
+	if err != nil {
+		return nil, err
+	}
+	if !info.Exists {
+		return nil, &os.PathError{Op: "stat", Path: filename, Err: os.ErrNotExist}
+	}
+
+	return osFileInfo{info}, nil
+}
+
+func (b *BillyFS) Rename(oldpath, newpath string) error {
+	newName := b.path(newpath)
+	if err := MkdirAll(filepath.Dir(newName), 0755); err != nil {
+		return err
+	}
+	return Rename(b.path(oldpath), newName)
+}
+
+func (b *BillyFS) Remove(filename string) error {
+	return Remove(b.path(filename))
+}
+
+func (b *BillyFS) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+func (b *BillyFS) TempFile(dir, prefix string) (_ billy.File, err error) {
+	fullDir := b.path(dir)
+	defer startOp("BillyFS.TempFile", fullDir).end(&err)
+	if err := injectedFault("BillyFS.TempFile", fullDir); err != nil {
+		return nil, err
+	}
+	if planned("create", filepath.Join(fullDir, prefix+"*"), "") {
+		return nil, ErrDryRun
+	}
+	if err := MkdirAll(fullDir, 0755); err != nil {
+		return nil, err
+	}
+
+	ioWait()
+	file, err := os.CreateTemp(longPath(fullDir), prefix)
+	ioDone()
+	if err != nil {
+		errorPrinter("BillyFS.TempFile (os.CreateTemp): "+err.Error(), fullDir)
+		return nil, err
+	}
+
+	fullName := filepath.Join(fullDir, filepath.Base(file.Name()))
+	cacheErr := firstError(UpdateFileInfo(fullName), UpdateDirectoryContents(fullDir))
+	publishInvalidation(fullName)
+	audit("create", fullName, "", 0)
+	if cacheErr != nil {
+		file.Close()
+		return nil, cacheErr
+	}
+
+	return &billyFile{File: file, name: filepath.Join(dir, filepath.Base(file.Name())), path: fullName, written: true}, nil
+}
+
+// ReadDir returns the cached listing; like ioutil.ReadDir a symlink is
+// described as such, not as its target
+func (b *BillyFS) ReadDir(path string) ([]os.FileInfo, error) {
+	contents, err := ReadDir(b.path(path))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(contents))
+	for _, entry := range contents {
+		infos = append(infos, osFileInfo{entry})
+	}
+	return infos, nil
+}
+
+func (b *BillyFS) MkdirAll(filename string, perm os.FileMode) error {
+	return MkdirAll(b.path(filename), perm)
+}
+
+// Lstat asks the disk, the cache describes the target of a symlink
+func (b *BillyFS) Lstat(filename string) (os.FileInfo, error) {
+	ioWait()
+	stat, err := os.Lstat(longPath(b.path(filename)))
+	ioDone()
+	return stat, err
+}
+
+func (b *BillyFS) Symlink(target, link string) (err error) {
+	fullName := b.path(link)
+	defer startOp("BillyFS.Symlink", fullName).end(&err)
+	if err := injectedFault("BillyFS.Symlink", fullName); err != nil {
+		return err
+	}
+	if planned("symlink", fullName, "") {
+		return nil
+	}
+	if err := MkdirAll(filepath.Dir(fullName), 0755); err != nil {
+		return err
+	}
+
+	ioWait()
+	err = os.Symlink(target, longPath(fullName))
+	ioDone()
+	if err != nil {
+		errorPrinter("BillyFS.Symlink (os.Symlink): "+err.Error(), fullName)
+		return err
+	}
+
+	// UpdateFileInfo would follow the link
+	CacheDelete(cacheKey(fullName))
+	cacheErr := UpdateDirectoryContents(filepath.Dir(fullName))
+	publishInvalidation(fullName)
+	audit("symlink", fullName, target, 0)
+	return cacheErr
+}
+
+func (b *BillyFS) Readlink(link string) (string, error) {
+	ioWait()
+	defer ioDone()
+	return os.Readlink(longPath(b.path(link)))
+}
+
+// Chroot returns the filesystem below path, which cannot be left with ".."
+func (b *BillyFS) Chroot(path string) (billy.Filesystem, error) {
+	return NewBillyFS(b.path(path)), nil
+}
+
+func (b *BillyFS) Root() string {
+	return b.root
+}
+
+// Chmod, Lchown, Chown and Chtimes implement billy.Change, go-git uses it to
+// set the executable bit on checkout
+
+func (b *BillyFS) Chmod(name string, mode os.FileMode) error {
+	return Chmod(b.path(name), mode)
+}
+
+func (b *BillyFS) Lchown(name string, uid, gid int) error {
+	return b.change(name, "lchown", func(fullName string) error { return os.Lchown(fullName, uid, gid) })
+}
+
+func (b *BillyFS) Chown(name string, uid, gid int) error {
+	return b.change(name, "chown", func(fullName string) error { return os.Chown(fullName, uid, gid) })
+}
+
+func (b *BillyFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return b.change(name, "chtimes", func(fullName string) error { return os.Chtimes(fullName, atime, mtime) })
+}
+
+// change applies a metadata change to name on disk and drops its cached entry
+func (b *BillyFS) change(name string, op string, apply func(fullName string) error) (err error) {
+	fullName := b.path(name)
+	method := "BillyFS." + strings.ToUpper(op[:1]) + op[1:]
+	defer startOp(method, fullName).end(&err)
+	if err := injectedFault(method, fullName); err != nil {
+		return err
+	}
+	if planned(op, fullName, "") {
+		return nil
+	}
+
+	ioWait()
+	err = apply(longPath(fullName))
+	ioDone()
+	if err != nil {
+		errorPrinter("BillyFS ("+op+"): "+err.Error(), fullName)
+		return err
+	}
+
+	CacheDelete(cacheKey(fullName))
+	publishInvalidation(fullName)
+	audit(op, fullName, "", 0)
+	return nil
+}
+
+type billyFile struct {
+	*os.File
+	name    string // As passed to the BillyFS, billy.File.Name returns it
+	path    string
+	written bool
+}
+
+func (f *billyFile) Name() string {
+	return f.name
+}
+
+func (f *billyFile) Lock() error {
+	return lockFile(f.File)
+}
+
+func (f *billyFile) Unlock() error {
+	return unlockFile(f.File)
+}
+
+func (f *billyFile) Close() error {
+	err := f.File.Close()
+	if f.written {
+		UpdateFileInfo(f.path)
+		CacheDelete(cacheKey(filepath.Dir(f.path)))
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package GMSFS
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCopyBufferSize matches the buffer io.Copy allocates on its own
+const DefaultCopyBufferSize = 32 * 1024
+
+var (
+	copyBufferSize int64 = DefaultCopyBufferSize
+	copyBuffers    sync.Pool
+)
+
+// SetCopyBufferSize sets the size of the pooled buffers used by CopyFile,
+// WriteFrom, ReadTo and the checksum helpers. Large-file workloads do better
+// with 1MB or more. Sizes below 4KB are raised to 4KB.
+func SetCopyBufferSize(size int) {
+	if size < 4096 {
+		size = 4096
+	}
+	atomic.StoreInt64(&copyBufferSize, int64(size))
+}
+
+func getCopyBuffer() *[]byte {
+	size := int(atomic.LoadInt64(&copyBufferSize))
+	if b, ok := copyBuffers.Get().(*[]byte); ok && len(*b) == size {
+		return b
+	}
+	b := make([]byte, size)
+	return &b
+}
+
+func putCopyBuffer(b *[]byte) {
+	// Buffers of an old size are left to the garbage collector
+	if len(*b) == int(atomic.LoadInt64(&copyBufferSize)) {
+		copyBuffers.Put(b)
+	}
+}
+
+// copyBuffered is io.Copy with a pooled buffer
+func copyBuffered(dst io.Writer, src io.Reader) (int64, error) {
+	b := getCopyBuffer()
+	defer putCopyBuffer(b)
+	return io.CopyBuffer(dst, src, *b)
+}
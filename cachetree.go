@@ -0,0 +1,105 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheChildren stores the entries of a directory listing under their own
+// keys and returns those keys, with the mode of every entry as the listing
+// (lstat) saw it. An existing child entry describing the same file is kept,
+// as it may hold more than the listing does (a checksum, or the listing of a
+// subdirectory).
+//
+// A child key holds what Stat returns, which follows symlinks, so the mode
+// of a link is kept by the directory: resolveChildren puts it back.
+func cacheChildren(dirKey string, dir FileInfo) ([]string, []os.FileMode) {
+	now := clockNow()
+	cacheTime := dir.CacheTime
+	if cacheTime.IsZero() {
+		cacheTime = now
+	}
+
+	keys := make([]string, 0, len(dir.Contents))
+	modes := make([]os.FileMode, 0, len(dir.Contents))
+	for _, child := range dir.Contents {
+		childKey := filepath.Join(dirKey, normalizeKey(strings.ToLower(child.Name)))
+		keys = append(keys, childKey)
+		modes = append(modes, child.Mode)
+
+		child.Contents = nil
+		if child.CacheTime.IsZero() {
+			child.CacheTime = cacheTime
+		}
+
+		if old, found := cache.Get(childKey); found {
+			oldInfo := old.Value.(FileInfo)
+			if child.Mode&os.ModeSymlink != 0 && oldInfo.Exists && oldInfo.Mode&os.ModeSymlink == 0 {
+				// Stat's entry of the link target, the listing cannot tell
+				// whether it is still current
+				continue
+			}
+			// The size of a directory differs between a listing and a stat
+			if oldInfo.Exists && oldInfo.IsDir == child.IsDir && (child.IsDir || oldInfo.Size == child.Size) &&
+				oldInfo.LastModified.Equal(child.LastModified) {
+				oldInfo.Name = child.Name
+				if child.CacheTime.After(oldInfo.CacheTime) {
+					oldInfo.CacheTime = child.CacheTime
+				}
+				old.Value = oldInfo
+				old.Timestamp = now
+				cacheSet(childKey, old)
+				continue
+			}
+		}
+		cacheSet(childKey, CacheItem{Value: child, Timestamp: now})
+	}
+
+	// Sets are buffered by ristretto, make the children visible before the
+	// directory entry that refers to them
+	cache.Wait()
+
+	return keys, modes
+}
+
+// caseCollision reports whether two entries of a listing differ only in case,
+// which a case sensitive disk allows. Their child keys would be the same, so
+// such a listing is cached with its Contents instead.
+func caseCollision(contents []FileInfo) bool {
+	seen := make(map[string]bool, len(contents))
+	for _, child := range contents {
+		key := normalizeKey(strings.ToLower(child.Name))
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+	}
+	return false
+}
+
+// resolveChildren fills Contents of a listed directory from its children's
+// entries. When a child has been dropped the listing is incomplete, and the
+// directory is returned without Contents so ReadDir reads it again.
+func resolveChildren(item CacheItem, value FileInfo) FileInfo {
+	contents := make([]FileInfo, 0, len(item.Children))
+	for i, childKey := range item.Children {
+		child, found := cache.Get(childKey)
+		if !found {
+			return value
+		}
+		childInfo := child.Value.(FileInfo)
+		if !childInfo.Exists || clockSince(childInfo.CacheTime) > cacheTTL(childKey)+MaxStaleTime {
+			return value
+		}
+		if i < len(item.ChildModes) && item.ChildModes[i].Type() != childInfo.Mode.Type() {
+			// A symlink Stat has followed, listed as the link itself
+			childInfo.Mode = item.ChildModes[i]
+			childInfo.IsDir = childInfo.Mode.IsDir()
+		}
+		contents = append(contents, childInfo)
+	}
+
+	value.Contents = contents
+	return value
+}
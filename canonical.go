@@ -0,0 +1,93 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Canonicalize returns the absolute path of name with every symlink resolved
+// and every component spelled the way it is stored on disk (see RealCase), so
+// two spellings of the same file compare equal. name must exist.
+func Canonicalize(name string) (_ string, err error) {
+	defer startOp("Canonicalize", name).end(&err)
+
+	abs, err := filepath.Abs(cleanPath(name))
+	if err != nil {
+		return "", err
+	}
+	// The case first, a case sensitive filesystem cannot resolve links of a
+	// misspelled path
+	abs, err = RealCase(abs)
+	if err != nil {
+		return "", err
+	}
+	ioWait()
+	resolved, err := filepath.EvalSymlinks(longPath(abs))
+	ioDone()
+	if err != nil {
+		return "", err
+	}
+	// Link targets are spelled however they were written
+	return RealCase(resolved)
+}
+
+var (
+	workDirMutex sync.RWMutex
+	workDir      string
+	workDirRead  bool
+)
+
+// Chdir changes the working directory like os.Chdir and re-anchors the cache
+// keys of relative paths, which are resolved against it. Calling os.Chdir
+// directly leaves relative names keyed under the old directory.
+func Chdir(dir string) error {
+	workDirMutex.Lock()
+	defer workDirMutex.Unlock()
+
+	if err := os.Chdir(longPath(dir)); err != nil {
+		return err
+	}
+	workDir, _ = os.Getwd()
+	workDirRead = true
+	return nil
+}
+
+// workingDir is the directory relative cache keys are resolved against,
+// read from the OS once
+func workingDir() string {
+	workDirMutex.RLock()
+	wd, read := workDir, workDirRead
+	workDirMutex.RUnlock()
+	if read {
+		return wd
+	}
+
+	workDirMutex.Lock()
+	defer workDirMutex.Unlock()
+	if !workDirRead {
+		workDir, _ = os.Getwd()
+		workDirRead = true
+	}
+	return workDir
+}
+
+// absPath makes the cleaned name absolute against the working directory, so
+// "data/x", "./data/x" and "/srv/data/x" share one cache key. Windows paths
+// on another host have no working directory to resolve against.
+func absPath(name string) string {
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || (WindowsPaths && runtime.GOOS != "windows") {
+		return name
+	}
+	wd := workingDir()
+	if wd == "" {
+		return name
+	}
+	if strings.HasPrefix(name, string(filepath.Separator)) {
+		// Rooted on the current drive
+		return filepath.VolumeName(wd) + name
+	}
+	return filepath.Join(wd, name)
+}
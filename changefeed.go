@@ -0,0 +1,81 @@
+package GMSFS
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChangeEvent is one mutation performed through GMSFS
+type ChangeEvent struct {
+	Seq     uint64 // Increases by one per change, a gap means events were dropped
+	Time    time.Time
+	Op      string // As in AuditEntry: "write", "append", "remove", "rename", ...
+	Path    string
+	NewPath string // Destination of renames and copies
+	OldSize int64
+	NewSize int64
+}
+
+var (
+	feedMutex       sync.Mutex
+	feedSubscribers = map[chan ChangeEvent]struct{}{}
+	feedCount       int32
+	feedSeq         uint64
+)
+
+// SubscribeChanges returns a feed of every mutation made through GMSFS in
+// this process, in the order they happened, independent of OS watchers. Only
+// changes made via GMSFS appear, use Watch for outside changes. A subscriber
+// that falls more than buffer events behind misses events, which shows as a
+// gap in Seq. cancel ends the subscription and closes the channel.
+func SubscribeChanges(buffer int) (events <-chan ChangeEvent, cancel func()) {
+	ch := make(chan ChangeEvent, buffer)
+
+	feedMutex.Lock()
+	feedSubscribers[ch] = struct{}{}
+	atomic.StoreInt32(&feedCount, int32(len(feedSubscribers)))
+	feedMutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			feedMutex.Lock()
+			delete(feedSubscribers, ch)
+			atomic.StoreInt32(&feedCount, int32(len(feedSubscribers)))
+			feedMutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func changeFeedActive() bool {
+	return atomic.LoadInt32(&feedCount) > 0
+}
+
+// publishChange turns an audit entry into a ChangeEvent for the subscribers.
+// The new size is taken from disk; the old one follows from the size delta.
+func publishChange(entry AuditEntry) {
+	event := ChangeEvent{Time: entry.Time, Op: entry.Op, Path: entry.Path, NewPath: entry.NewPath}
+	target := entry.Path
+	if entry.NewPath != "" {
+		target = entry.NewPath
+	}
+	if stat, err := os.Lstat(longPath(target)); err == nil && !stat.IsDir() {
+		event.NewSize = stat.Size()
+	}
+	event.OldSize = event.NewSize - entry.SizeDelta
+
+	// Numbered and sent under the lock, so every subscriber sees one order
+	feedMutex.Lock()
+	defer feedMutex.Unlock()
+	feedSeq++
+	event.Seq = feedSeq
+	for ch := range feedSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
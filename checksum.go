@@ -0,0 +1,93 @@
+package GMSFS
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var checksumOnWrite int32
+
+// SetChecksumOnWrite makes WriteFile, WriteFrom and CopyFile hash what they
+// write and cache the SHA256, so a later FileSHA256 costs nothing. Append
+// drops the cached checksum instead, a SHA256 cannot be extended from its
+// digest.
+func SetChecksumOnWrite(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&checksumOnWrite, v)
+}
+
+func checksumOnWriteEnabled() bool {
+	return atomic.LoadInt32(&checksumOnWrite) == 1
+}
+
+// cacheChecksum caches the entry of a file just written together with the
+// SHA256 of its contents
+func cacheChecksum(name string, stat os.FileInfo, sum string) {
+	CacheAdd(cacheKey(name), FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		Name:         filepath.Base(name),
+		CacheTime:    clockNow(),
+		SHA256:       sum,
+	})
+}
+
+// FileSHA256 returns the hex encoded SHA256 of a file. The checksum is kept in
+// the cached FileInfo, so it is only recomputed after the entry changes.
+func FileSHA256(name string) (string, error) {
+	name = cleanPath(name)
+	lowerCaseName := cacheKey(name)
+
+	info, err := Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir {
+		return "", fmt.Errorf("%s is a directory", name)
+	}
+	if info.SHA256 != "" {
+		return info.SHA256, nil
+	}
+
+	sum, err := sha256File(name)
+	if err != nil {
+		errorPrinter("FileSHA256 (sha256File): "+err.Error(), name)
+		return "", err
+	}
+
+	// Only onto the entry that was hashed, the file may have changed since
+	CacheUpdate(lowerCaseName, func(current FileInfo, ok bool) (FileInfo, bool) {
+		current.SHA256 = sum
+		return current, ok && sameFileState(current, info)
+	})
+
+	return sum, nil
+}
+
+// sha256File hashes name straight from disk, without looking at the cache
+func sha256File(name string) (string, error) {
+	ioWait()
+	defer ioDone()
+
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := copyBuffered(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
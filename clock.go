@@ -0,0 +1,70 @@
+package GMSFS
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock supplies the time used for cache ages and expiry
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+type clockHolder struct{ Clock }
+
+var currentClock atomic.Value
+
+func init() {
+	currentClock.Store(clockHolder{systemClock{}})
+}
+
+// SetClock replaces the clock of the cache TTL logic, nil restores the system
+// clock. Tests use it with a ManualClock to step through expiry.
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	currentClock.Store(clockHolder{c})
+}
+
+func clockNow() time.Time {
+	return currentClock.Load().(clockHolder).Now()
+}
+
+func clockSince(t time.Time) time.Duration {
+	return clockNow().Sub(t)
+}
+
+// ManualClock is a Clock that only moves when told to
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (mc *ManualClock) Now() time.Time {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.now
+}
+
+// Advance moves the clock forward by d
+func (mc *ManualClock) Advance(d time.Duration) {
+	mc.mutex.Lock()
+	mc.now = mc.now.Add(d)
+	mc.mutex.Unlock()
+}
+
+func (mc *ManualClock) Set(t time.Time) {
+	mc.mutex.Lock()
+	mc.now = t
+	mc.mutex.Unlock()
+}
@@ -0,0 +1,66 @@
+package GMSFS_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inpadi/GMSFS"
+)
+
+func TestManualClockExpiresEntries(t *testing.T) {
+	clock := GMSFS.NewManualClock(time.Now())
+	GMSFS.SetClock(clock)
+	defer GMSFS.SetClock(nil)
+
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GMSFS.Stat(name); err != nil {
+		t.Fatal(err)
+	}
+	GMSFS.CacheWait()
+
+	// Behind the cache's back, the cached entry still says it exists
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := GMSFS.Stat(name); err != nil || !info.Exists {
+		t.Fatalf("Stat before expiry = %+v, %v, want the cached entry", info, err)
+	}
+
+	clock.Advance(GMSFS.MaxCacheTime + GMSFS.MaxStaleTime + time.Second)
+	if _, err := GMSFS.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("Stat after expiry = %v, want not exist", err)
+	}
+}
+
+func TestFileAgeUsesClock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	GMSFS.SetClock(GMSFS.NewManualClock(stat.ModTime().Add(time.Hour)))
+	defer GMSFS.SetClock(nil)
+
+	age, err := GMSFS.FileAgeInSec(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if age != time.Hour {
+		t.Errorf("FileAgeInSec = %v, want 1h", age)
+	}
+	if !GMSFS.IsOlderThan(name, 30*time.Minute) {
+		t.Error("IsOlderThan(30m) = false an hour after the modification")
+	}
+	if GMSFS.ModifiedWithin(name, 30*time.Minute) {
+		t.Error("ModifiedWithin(30m) = true an hour after the modification")
+	}
+}
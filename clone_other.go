@@ -0,0 +1,11 @@
+//go:build !linux
+
+package GMSFS
+
+import "os"
+
+// cloneFile has no portable equivalent of FICLONE outside Linux, CopyFile uses
+// a regular copy there
+func cloneFile(out *os.File, in *os.File) bool {
+	return false
+}
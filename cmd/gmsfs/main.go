@@ -0,0 +1,304 @@
+// Command gmsfs inspects GMSFS caches and trees from the shell.
+//
+//	gmsfs cache stats  [-load file]
+//	gmsfs cache dump   [-load file] [prefix]
+//	gmsfs du     [-agent url] path
+//	gmsfs find   [-agent url] [-name glob] path
+//	gmsfs diff   [-agent url] pathA pathB
+//	gmsfs verify [-load file] [-repair] path
+//
+// With -agent the tree is read through a GMSFS agent (see GMSFS.NewAgent),
+// so the output shows what the service running the agent sees. With -load a
+// cache saved by GMSFS.SaveCache is inspected instead of a fresh one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/inpadi/GMSFS"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "cache":
+		err = cacheCmd(os.Args[2:])
+	case "du":
+		err = duCmd(os.Args[2:])
+	case "find":
+		err = findCmd(os.Args[2:])
+	case "diff":
+		err = diffCmd(os.Args[2:])
+	case "verify":
+		err = verifyCmd(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gmsfs:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gmsfs cache stats|dump | du | find | diff | verify  [flags] [paths]")
+	os.Exit(2)
+}
+
+// backendFor returns the tree to inspect and the name of path inside it
+func backendFor(agent string, p string) (GMSFS.Backend, string, error) {
+	if agent != "" {
+		return GMSFS.NewCachedBackend("agent://"+agent, GMSFS.NewAgentClient(agent)), p, nil
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, "", err
+	}
+	return &GMSFS.LocalBackend{Root: filepath.Dir(abs)}, "/" + filepath.Base(abs), nil
+}
+
+// walk calls fn for every entry below name, depth first in name order
+func walk(b GMSFS.Backend, name string, fn func(name string, info GMSFS.FileInfo)) error {
+	contents, err := b.ReadDir(name)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range contents {
+		child := path.Join(name, entry.Name)
+		fn(child, entry)
+		if entry.IsDir && entry.Mode&os.ModeSymlink == 0 {
+			if err := walk(b, child, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func loadCache(file string) error {
+	if file == "" {
+		return nil
+	}
+	if _, err := GMSFS.LoadCache(file); err != nil {
+		return err
+	}
+	// The restored entries are buffered by ristretto, count them once stored
+	GMSFS.CacheWait()
+	return nil
+}
+
+func cacheCmd(args []string) error {
+	if len(args) < 1 {
+		usage()
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	load := fs.String("load", "", "cache file written by SaveCache")
+	fs.Parse(args[1:])
+
+	if err := loadCache(*load); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "stats":
+		stats := GMSFS.CacheStats()
+		fmt.Printf("entries:  %d\n", stats.Entries)
+		fmt.Printf("files:    %d\n", stats.Files)
+		fmt.Printf("dirs:     %d\n", stats.Dirs)
+		fmt.Printf("negative: %d\n", stats.Negative)
+		fmt.Printf("bytes:    %d\n", stats.Bytes)
+	case "dump":
+		prefix := strings.ToLower(fs.Arg(0))
+		entries := GMSFS.CacheEntries()
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			info := entries[key]
+			fmt.Printf("%s\t%v\t%d\t%s\t%s\n", key, info.Exists, info.Size, info.Mode,
+				info.LastModified.Format("2006-01-02T15:04:05"))
+		}
+	default:
+		usage()
+	}
+
+	return nil
+}
+
+func duCmd(args []string) error {
+	fs := flag.NewFlagSet("du", flag.ExitOnError)
+	agent := fs.String("agent", "", "agent base URL")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	b, name, err := backendFor(*agent, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var files int
+	var total int64
+	err = walk(b, name, func(_ string, info GMSFS.FileInfo) {
+		if !info.IsDir {
+			files++
+			total += info.Size
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d\t%d files\t%s\n", total, files, fs.Arg(0))
+	return nil
+}
+
+func findCmd(args []string) error {
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	agent := fs.String("agent", "", "agent base URL")
+	pattern := fs.String("name", "*", "glob the base name must match (case insensitive)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	b, name, err := backendFor(*agent, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var matchErr error
+	err = walk(b, name, func(p string, info GMSFS.FileInfo) {
+		ok, err := path.Match(strings.ToLower(*pattern), strings.ToLower(info.Name))
+		if err != nil {
+			matchErr = err
+		}
+		if ok {
+			fmt.Println(path.Join(fs.Arg(0), strings.TrimPrefix(p, name)))
+		}
+	})
+	if matchErr != nil {
+		return matchErr
+	}
+	return err
+}
+
+func diffCmd(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	agent := fs.String("agent", "", "agent base URL")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+
+	listing := func(p string) (map[string]GMSFS.FileInfo, error) {
+		b, name, err := backendFor(*agent, p)
+		if err != nil {
+			return nil, err
+		}
+		entries := map[string]GMSFS.FileInfo{}
+		err = walk(b, name, func(child string, info GMSFS.FileInfo) {
+			entries[strings.TrimPrefix(child, name)] = info
+		})
+		return entries, err
+	}
+
+	a, err := listing(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := listing(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		ai, inA := a[name]
+		bi, inB := b[name]
+		switch {
+		case !inB:
+			fmt.Println("-", name)
+		case !inA:
+			fmt.Println("+", name)
+		case ai.IsDir != bi.IsDir || (!ai.IsDir && ai.Size != bi.Size):
+			fmt.Println("~", name)
+		}
+	}
+	return nil
+}
+
+func verifyCmd(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	load := fs.String("load", "", "cache file written by SaveCache")
+	repair := fs.Bool("repair", false, "drop or refresh divergent entries")
+	workers := fs.Int("workers", 8, "concurrent directory reads when warming")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	root, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// Without a saved cache, warm one so there is something to verify
+	if *load != "" {
+		err = loadCache(*load)
+	} else {
+		err = GMSFS.Warm(root, -1, *workers)
+	}
+	if err != nil {
+		return err
+	}
+
+	validate := GMSFS.ValidateCache
+	if *repair {
+		validate = GMSFS.RepairCache
+	}
+	report, err := validate(root)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range report.Divergences {
+		fmt.Printf("%s\t%s\n", d.Kind, d.Key)
+	}
+	fmt.Printf("checked %d entries, %d divergent, %d repaired\n", report.Checked, len(report.Divergences), report.Repaired)
+	if len(report.Divergences) > 0 && !*repair {
+		os.Exit(1)
+	}
+	return nil
+}
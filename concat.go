@@ -0,0 +1,103 @@
+package GMSFS
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// concatReader reads the files in names one after the other, opening each
+// only when the previous one is exhausted
+type concatReader struct {
+	names []string
+	cur   *os.File
+}
+
+func (cr *concatReader) Read(p []byte) (int, error) {
+	for {
+		if cr.cur == nil {
+			if len(cr.names) == 0 {
+				return 0, io.EOF
+			}
+			ioWait()
+			f, err := os.Open(longPath(cr.names[0]))
+			ioDone()
+			if err != nil {
+				return 0, err
+			}
+			cr.cur = f
+			cr.names = cr.names[1:]
+		}
+
+		n, err := cr.cur.Read(p)
+		if err == io.EOF {
+			cr.cur.Close()
+			cr.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (cr *concatReader) Close() {
+	if cr.cur != nil {
+		cr.cur.Close()
+	}
+}
+
+// ConcatFiles streams srcs, in order, into dst, creating or truncating it.
+// dst must not be one of srcs.
+func ConcatFiles(dst string, srcs ...string) error {
+	for _, src := range srcs {
+		if cacheKey(src) == cacheKey(dst) {
+			return fmt.Errorf("concat: %s is both source and destination", dst)
+		}
+	}
+
+	r := &concatReader{names: srcs}
+	defer r.Close()
+
+	_, err := WriteFrom(dst, r, 0644)
+	return err
+}
+
+// SplitFile cuts src into chunks of chunkSize bytes and returns their names.
+// dstPattern names the chunks with an integer verb, e.g. "upload.part%03d",
+// numbered from 0; the last chunk may be shorter.
+func SplitFile(src string, chunkSize int64, dstPattern string) ([]string, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("split: invalid chunk size %d", chunkSize)
+	}
+
+	ioWait()
+	f, err := os.Open(longPath(src))
+	ioDone()
+	if err != nil {
+		errorPrinter("SplitFile (os.Open): "+err.Error(), src)
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []string
+	for i := 0; int64(i)*chunkSize < stat.Size(); i++ {
+		name := fmt.Sprintf(dstPattern, i)
+		if cacheKey(name) == cacheKey(src) {
+			return chunks, fmt.Errorf("split: chunk %s would overwrite %s", name, src)
+		}
+
+		if _, err := WriteFrom(name, io.LimitReader(f, chunkSize), stat.Mode().Perm()); err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, name)
+	}
+
+	return chunks, nil
+}
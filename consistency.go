@@ -0,0 +1,68 @@
+package GMSFS
+
+import (
+	"context"
+)
+
+// Consistency selects whether a call may be answered from the cache
+type Consistency int
+
+const (
+	Cached Consistency = iota // Answer from the cache when possible (default)
+	Strong                    // Always ask the filesystem and refresh the cache
+)
+
+type consistencyKey struct{}
+
+// WithConsistency returns a context that makes the *Context functions use level
+func WithConsistency(ctx context.Context, level Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, level)
+}
+
+func consistencyFrom(ctx context.Context) Consistency {
+	if level, ok := ctx.Value(consistencyKey{}).(Consistency); ok {
+		return level
+	}
+	return Cached
+}
+
+// StatStrong bypasses the cache, stats name on disk and refreshes its entry.
+// Use it where acting on stale metadata is not acceptable (billing, deletes).
+func StatStrong(name string) (FileInfo, error) {
+	CacheDelete(cacheKey(name))
+	return Stat(name)
+}
+
+// ReadDirStrong re-reads dirName from disk and refreshes its cached listing
+func ReadDirStrong(dirName string) ([]FileInfo, error) {
+	CacheDelete(cacheKey(dirName))
+	return ReadDir(dirName)
+}
+
+// FileExistsStrong checks name on disk, ignoring any cached (negative) entry
+func FileExistsStrong(name string) bool {
+	_, err := StatStrong(name)
+	return err == nil
+}
+
+func StatContext(ctx context.Context, name string) (FileInfo, error) {
+	if consistencyFrom(ctx) == Strong {
+		CacheDelete(cacheKey(name))
+	}
+	return statContext(ctx, name)
+}
+
+func ReadDirContext(ctx context.Context, dirName string) ([]FileInfo, error) {
+	if consistencyFrom(ctx) == Strong {
+		CacheDelete(cacheKey(dirName))
+	}
+	return readDirContext(ctx, dirName)
+}
+
+func FileExistsContext(ctx context.Context, name string) bool {
+	if consistencyFrom(ctx) == Strong {
+		_, err := StatContext(ctx, name)
+		return err == nil
+	}
+	return FileExists(name)
+}
@@ -0,0 +1,64 @@
+package GMSFS
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ContentType returns the MIME type of name, from its extension or else by
+// sniffing the first 512 bytes. The result is kept in the cached FileInfo, so
+// it is only determined again after the file changed.
+func ContentType(name string) (string, error) {
+	name = cleanPath(name)
+	lowerCaseName := cacheKey(name)
+
+	info, err := Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir {
+		return "", fmt.Errorf("%s is a directory", name)
+	}
+	if info.ContentType != "" {
+		return info.ContentType, nil
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType, err = sniffContentType(name)
+		if err != nil {
+			errorPrinter("ContentType (sniffContentType): "+err.Error(), name)
+			return "", err
+		}
+	}
+
+	CacheUpdate(lowerCaseName, func(current FileInfo, ok bool) (FileInfo, bool) {
+		current.ContentType = contentType
+		return current, ok && sameFileState(current, info)
+	})
+
+	return contentType, nil
+}
+
+func sniffContentType(name string) (string, error) {
+	ioWait()
+	defer ioDone()
+
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
@@ -0,0 +1,166 @@
+package GMSFS
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CopyOption configures CopyDir and CopyFile, which only uses
+// CopyPreserveMetadata
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	include []string
+	exclude []string
+	filter  func(rel string, fi FileInfo) bool
+
+	symlinks  SymlinkPolicy
+	ancestors []os.FileInfo
+
+	preserve bool
+}
+
+func applyCopyOptions(opts []CopyOption) copyOptions {
+	options := copyOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// fileOptions are the options CopyDir passes on to CopyFile
+func (o *copyOptions) fileOptions() []CopyOption {
+	if o.preserve {
+		return []CopyOption{CopyPreserveMetadata()}
+	}
+	return nil
+}
+
+// CopyInclude only copies files matching one of patterns (filepath.Match
+// syntax, against the name or the slash separated path below the source).
+// Directories are still descended, use CopyExclude to prune them.
+func CopyInclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) { o.include = append(o.include, patterns...) }
+}
+
+// CopyExclude skips files and directories matching one of patterns, e.g.
+// "node_modules" or "*.tmp"
+func CopyExclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) { o.exclude = append(o.exclude, patterns...) }
+}
+
+// CopyFilter skips entries for which filter returns false. rel is the path
+// below the source and fi the cached FileInfo, so size or age limits need no
+// extra stat:
+//
+//	CopyFilter(func(rel string, fi FileInfo) bool { return fi.IsDir || fi.Size < 100<<20 })
+func CopyFilter(filter func(rel string, fi FileInfo) bool) CopyOption {
+	return func(o *copyOptions) { o.filter = filter }
+}
+
+// selected reports whether the entry rel is copied
+func (o *copyOptions) selected(rel string, fi FileInfo) bool {
+	if matchAny(o.exclude, rel, fi.Name) {
+		return false
+	}
+	if !fi.IsDir && len(o.include) > 0 && !matchAny(o.include, rel, fi.Name) {
+		return false
+	}
+	return o.filter == nil || o.filter(rel, fi)
+}
+
+func matchAny(patterns []string, rel string, name string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyPreserveMetadata keeps the modification time of copied files and
+// directories, not only their mode. On Linux the access time, the owner and
+// group (when permitted, usually as root) and the extended attributes are
+// kept too; elsewhere the access time is set to the modification time.
+func CopyPreserveMetadata() CopyOption {
+	return func(o *copyOptions) { o.preserve = true }
+}
+
+// SymlinkPolicy is how CopyDir treats symlinks
+type SymlinkPolicy int
+
+const (
+	// SymlinkSkip leaves symlinks out of the copy, the default
+	SymlinkSkip SymlinkPolicy = iota
+	// SymlinkCopyLink recreates symlinks with the same target
+	SymlinkCopyLink
+	// SymlinkFollow copies what symlinks point to. A link to a directory that
+	// is already being copied (a cycle) fails the copy.
+	SymlinkFollow
+)
+
+// CopySymlinks sets the symlink policy of CopyDir
+func CopySymlinks(policy SymlinkPolicy) CopyOption {
+	return func(o *copyOptions) { o.symlinks = policy }
+}
+
+// copySymlink copies the symlink src to dst according to the symlink policy
+func copySymlink(src string, dst string, rel string, options *copyOptions) error {
+	switch options.symlinks {
+	case SymlinkCopyLink:
+		if planned("symlink", dst, "") {
+			return nil
+		}
+		ioWait()
+		target, err := os.Readlink(longPath(src))
+		if err == nil {
+			err = os.Symlink(target, longPath(dst))
+		}
+		ioDone()
+		if err != nil {
+			return err
+		}
+		// UpdateFileInfo would follow the link
+		CacheDelete(cacheKey(dst))
+		cacheErr := UpdateDirectoryContents(filepath.Dir(dst))
+		publishInvalidation(dst)
+		audit("symlink", dst, target, 0)
+		if options.preserve {
+			if err := preserveLinkMetadata(src, dst); err != nil {
+				return err
+			}
+		}
+		return cacheErr
+
+	case SymlinkFollow:
+		ioWait()
+		stat, err := os.Stat(longPath(src))
+		ioDone()
+		if err != nil {
+			return err
+		}
+		if !stat.IsDir() {
+			return CopyFile(src, dst, options.fileOptions()...)
+		}
+		for _, ancestor := range options.ancestors {
+			if os.SameFile(stat, ancestor) {
+				return fmt.Errorf("symlink cycle: %s points to a directory being copied", src)
+			}
+		}
+		// The cached entry of a listed symlink describes the link itself
+		if err := UpdateFileInfo(src); err != nil {
+			return err
+		}
+		if err := copyDir(src, dst, rel, options); err != nil {
+			return err
+		}
+		return UpdateDirectoryContents(dst)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package GMSFS
+
+import "unsafe"
+
+var (
+	fileInfoSize  = int64(unsafe.Sizeof(FileInfo{}))
+	cacheItemSize = int64(unsafe.Sizeof(CacheItem{}))
+	stringSize    = int64(unsafe.Sizeof(""))
+)
+
+// cacheCost estimates the memory held by a cache entry in bytes, so that
+// ristretto's MaxCost bounds the memory used by the cache
+func cacheCost(item CacheItem) int64 {
+	cost := cacheItemSize + int64(len(item.Key))
+	if value, ok := item.Value.(FileInfo); ok {
+		cost += fileInfoCost(value)
+	}
+	for _, child := range item.Children {
+		cost += stringSize + int64(len(child))
+	}
+	cost += int64(len(item.ChildModes)) * int64(unsafe.Sizeof(item.ChildModes[0]))
+
+	return cost
+}
+
+func fileInfoCost(info FileInfo) int64 {
+	cost := fileInfoSize + int64(len(info.Name)+len(info.SHA256))
+	for _, child := range info.Contents {
+		cost += fileInfoCost(child)
+	}
+
+	return cost
+}
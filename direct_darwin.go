@@ -0,0 +1,23 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	directFlag      = 0
+	directSupported = true
+)
+
+// setDirect toggles F_NOCACHE, macOS has no O_DIRECT
+func setDirect(f *os.File, on bool) error {
+	value := uintptr(0)
+	if on {
+		value = 1
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_NOCACHE, value); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	directFlag      = syscall.O_DIRECT
+	directSupported = true
+)
+
+// setDirect toggles O_DIRECT on an open file
+func setDirect(f *os.File, on bool) error {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_GETFL, 0)
+	if errno != 0 {
+		return errno
+	}
+	if on {
+		flags |= syscall.O_DIRECT
+	} else {
+		flags &^= syscall.O_DIRECT
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_SETFL, flags); errno != 0 {
+		return errno
+	}
+	return nil
+}
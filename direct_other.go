@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package GMSFS
+
+import "os"
+
+const (
+	directFlag      = 0
+	directSupported = false
+)
+
+func setDirect(f *os.File, on bool) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,67 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DirEntry is an entry returned by ReadDirEntries. Name and type come from the
+// directory read itself, the full FileInfo is only stat'ed when Info is called.
+type DirEntry struct {
+	Name  string
+	IsDir bool
+	Type  os.FileMode // Type bits only, see fs.FileMode.Type
+
+	path string
+	info *FileInfo // Known when answered from a cached listing
+}
+
+// Info returns the FileInfo of the entry, using the cache where possible
+func (de DirEntry) Info() (FileInfo, error) {
+	if de.info != nil {
+		return *de.info, nil
+	}
+	return Stat(de.path)
+}
+
+// ReadDirEntries lists dirName without stating every entry like ReadDir does.
+// A cached listing is used when present, otherwise the names-only read is not
+// cached since it lacks sizes and times.
+func ReadDirEntries(dirName string) ([]DirEntry, error) {
+	if fc, ok := CacheGet(cacheKey(dirName)); ok && fc.Contents != nil {
+		entries := make([]DirEntry, 0, len(fc.Contents))
+		for i := range fc.Contents {
+			info := fc.Contents[i]
+			entries = append(entries, DirEntry{
+				Name:  info.Name,
+				IsDir: info.IsDir,
+				Type:  info.Mode.Type(),
+				path:  filepath.Join(dirName, info.Name),
+				info:  &info,
+			})
+		}
+		return entries, nil
+	}
+
+	ioWait()
+	dirs, err := os.ReadDir(longPath(dirName))
+	ioDone()
+	if err != nil {
+		logf("ReadDirEntries (os.ReadDir): %v", err)
+		return nil, err
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+
+	entries := make([]DirEntry, 0, len(dirs))
+	for _, entry := range dirs {
+		entries = append(entries, DirEntry{
+			Name:  entry.Name(),
+			IsDir: entry.IsDir(),
+			Type:  entry.Type(),
+			path:  filepath.Join(dirName, entry.Name()),
+		})
+	}
+
+	return entries, nil
+}
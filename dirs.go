@@ -0,0 +1,173 @@
+package GMSFS
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// EnsureDir creates path and its parents like MkdirAll and verifies the
+// result is a directory, a regular file in the way is an error. An existing
+// directory is answered from the cache.
+func EnsureDir(path string, perm os.FileMode) (err error) {
+	defer startOp("EnsureDir", path).end(&err)
+	if err := injectedFault("EnsureDir", path); err != nil {
+		return err
+	}
+	path = cleanPath(path)
+
+	if err := MkdirAll(path, perm); err != nil {
+		return err
+	}
+	if dryRunning() {
+		return nil
+	}
+
+	info, err := Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		return &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+// IsEmptyDir reports whether the directory path has no entries, from the
+// cached listing when there is one
+func IsEmptyDir(path string) (bool, error) {
+	info, err := Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir {
+		return false, fmt.Errorf("%s is not a directory", path)
+	}
+
+	entries, err := ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// EmptyDir removes everything inside the directory path and keeps path
+// itself. The children are taken from the cached listing and the directory
+// cache is refreshed once at the end.
+func EmptyDir(path string) (err error) {
+	defer startOp("EmptyDir", path).end(&err)
+	if err := injectedFault("EmptyDir", path); err != nil {
+		return err
+	}
+	path = cleanPath(path)
+
+	info, err := Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	entries, err := ReadDir(path)
+	if err != nil {
+		return err
+	}
+	_, err = removeEntries(path, entries)
+	return err
+}
+
+// PruneEmptyDirs removes the directories below root that are empty, or
+// become empty once their empty subdirectories are gone, and keeps root
+// itself. It returns how many directories were removed.
+func PruneEmptyDirs(root string) (removed int, err error) {
+	defer startOp("PruneEmptyDirs", root).end(&err)
+	if err := injectedFault("PruneEmptyDirs", root); err != nil {
+		return 0, err
+	}
+	root = cleanPath(root)
+
+	// prune reports whether dir is empty after pruning its subdirectories
+	var prune func(dir string) (bool, error)
+	prune = func(dir string) (bool, error) {
+		entries, err := ReadDir(dir)
+		if err != nil {
+			return false, err
+		}
+		empty, changed := true, false
+		for _, entry := range entries {
+			if !entry.IsDir || entry.Mode&os.ModeSymlink != 0 {
+				empty = false
+				continue
+			}
+			sub := filepath.Join(dir, entry.Name)
+			subEmpty, err := prune(sub)
+			if err != nil {
+				return false, err
+			}
+			if !subEmpty || planned("remove", sub, "") {
+				empty = false
+				continue
+			}
+			ioWait()
+			err = os.Remove(longPath(sub))
+			ioDone()
+			if err != nil {
+				// Filled in the meantime
+				empty = false
+				continue
+			}
+			removed++
+			changed = true
+			CacheInvalidatePrefix(sub)
+			publishTreeInvalidation(sub)
+			audit("remove", sub, "", 0)
+		}
+		if changed {
+			if err := UpdateDirectoryContents(dir); err != nil {
+				return false, err
+			}
+		}
+		return empty, nil
+	}
+
+	_, err = prune(root)
+	return removed, err
+}
+
+// removeEntries removes entries of dir, directories with their contents, and
+// refreshes the cached listing of dir once instead of after every entry. It
+// returns how many entries were removed and the first error.
+func removeEntries(dir string, entries []FileInfo) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	removed := 0
+	var firstErr error
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name)
+		if planned("removeall", name, "") {
+			continue
+		}
+		before := auditSize(name)
+
+		ioWait()
+		err := os.RemoveAll(longPath(name))
+		ioDone()
+		CacheInvalidatePrefix(name)
+		if err != nil {
+			errorPrinter("removeEntries (os.RemoveAll): "+err.Error(), name)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		removed++
+		publishTreeInvalidation(name)
+		audit("removeall", name, "", -before)
+	}
+
+	return removed, firstError(firstErr, UpdateDirectoryContents(dir))
+}
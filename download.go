@@ -0,0 +1,194 @@
+package GMSFS
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DownloadOption configures Download
+type DownloadOption func(*downloadOptions)
+
+type downloadOptions struct {
+	client *http.Client
+	sha256 string
+	header http.Header
+}
+
+// DownloadClient sends the requests with client instead of http.DefaultClient
+func DownloadClient(client *http.Client) DownloadOption {
+	return func(o *downloadOptions) { o.client = client }
+}
+
+// DownloadSHA256 verifies the finished download against a hex checksum
+// before it is renamed into place
+func DownloadSHA256(sum string) DownloadOption {
+	return func(o *downloadOptions) { o.sha256 = strings.ToLower(sum) }
+}
+
+// DownloadHeader adds a request header, e.g. for authorization
+func DownloadHeader(key string, value string) DownloadOption {
+	return func(o *downloadOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// Download fetches url into name, see DownloadContext
+func Download(url string, name string, opts ...DownloadOption) error {
+	return DownloadContext(context.Background(), url, name, opts...)
+}
+
+// DownloadContext streams url into name+".part" and renames it to name once
+// complete (and verified, with DownloadSHA256). A .part left by an earlier
+// attempt is resumed with a Range request; the ETag of the first response, or
+// its Last-Modified without one, is kept next to it in name+".part.validator"
+// and sent as If-Range, so a changed file on the server restarts the download
+// instead of being spliced. Without a validator the download starts over.
+func DownloadContext(ctx context.Context, url string, name string, opts ...DownloadOption) (err error) {
+	op := startOpContext(ctx, "Download", name)
+	defer op.end(&err)
+	if err := injectedFault("Download", name); err != nil {
+		return err
+	}
+	if planned("download", name, "") {
+		return nil
+	}
+
+	options := downloadOptions{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	name = cleanPath(name)
+	part := name + ".part"
+	validatorFile := part + ".validator"
+
+	var offset int64
+	validator, verr := os.ReadFile(longPath(validatorFile))
+	if stat, err := os.Stat(longPath(part)); err == nil && verr == nil && len(validator) > 0 {
+		offset = stat.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range options.header {
+		req.Header[key] = values
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", string(validator))
+	}
+
+	resp, err := options.client.Do(req)
+	if err != nil {
+		errorPrinter("Download (Do): "+err.Error(), url)
+		return err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start, ok := rangeStart(resp); !ok || start != offset {
+			// Appending would splice the wrong bytes, the next attempt starts over
+			os.Remove(longPath(part))
+			os.Remove(longPath(validatorFile))
+			return fmt.Errorf("download %s: resumed with Content-Range %q, want bytes from %d", url, resp.Header.Get("Content-Range"), offset)
+		}
+		flag |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && rangeComplete(resp, offset):
+		// The .part already holds everything
+		flag = 0
+	case resp.StatusCode == http.StatusOK:
+		flag |= os.O_TRUNC
+		os.Remove(longPath(validatorFile))
+		// If-Range takes a strong ETag or a date
+		validator := resp.Header.Get("ETag")
+		if validator == "" || strings.HasPrefix(validator, "W/") {
+			validator = resp.Header.Get("Last-Modified")
+		}
+		if validator != "" {
+			os.WriteFile(longPath(validatorFile), []byte(validator), 0644)
+		}
+	default:
+		return fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+
+	if flag != 0 {
+		ioWait()
+		f, err := os.OpenFile(longPath(part), flag, 0644)
+		ioDone()
+		if err != nil {
+			errorPrinter("Download (os.OpenFile): "+err.Error(), part)
+			return err
+		}
+
+		written, err := copyBuffered(f, resp.Body)
+		op.bytes(written)
+		if err == nil && syncOnClose() {
+			err = f.Sync()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			// Keep the .part, the next attempt resumes from here
+			errorPrinter("Download (copyBuffered): "+err.Error(), part)
+			return err
+		}
+	}
+
+	sum := ""
+	if options.sha256 != "" || checksumOnWriteEnabled() {
+		if sum, err = sha256File(part); err != nil {
+			return err
+		}
+		if options.sha256 != "" && sum != options.sha256 {
+			os.Remove(longPath(part))
+			os.Remove(longPath(validatorFile))
+			return fmt.Errorf("download %s: checksum mismatch, got %s, want %s", url, sum, options.sha256)
+		}
+	}
+
+	if err := Rename(part, name); err != nil {
+		return err
+	}
+	os.Remove(longPath(validatorFile))
+
+	if sum != "" {
+		if stat, err := os.Stat(longPath(name)); err == nil {
+			cacheChecksum(name, stat, sum)
+		}
+	} else {
+		return UpdateFileInfo(name)
+	}
+
+	return nil
+}
+
+// rangeComplete reports whether a 416 response says the resource is exactly
+// size bytes long ("Content-Range: bytes */size")
+func rangeComplete(resp *http.Response, size int64) bool {
+	total := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes */")
+	n, err := strconv.ParseInt(total, 10, 64)
+	return err == nil && n == size
+}
+
+// rangeStart returns the first byte of a 206 response
+// ("Content-Range: bytes start-end/total")
+func rangeStart(resp *http.Response) (int64, bool) {
+	spec := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	dash := strings.IndexByte(spec, '-')
+	if dash <= 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(spec[:dash], 10, 64)
+	return start, err == nil
+}
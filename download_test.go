@@ -0,0 +1,94 @@
+package GMSFS_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/inpadi/GMSFS"
+)
+
+func TestDownloadRestartsWithoutValidator(t *testing.T) {
+	content := []byte("the new version of the file")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	// Left by an attempt at an older version, with nothing to tell them apart
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name+".part", []byte("the old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GMSFS.Download(server.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %q, want %q", got, content)
+	}
+}
+
+func TestDownloadChecksContentRange(t *testing.T) {
+	content := []byte("0123456789")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ignores the requested start
+		w.Header().Set("Content-Range", "bytes 0-9/10")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name+".part", content[:4], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".part.validator", []byte(`"v1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GMSFS.Download(server.URL, name); err == nil {
+		t.Fatal("Download appended a range that does not start at the .part size")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("%s exists after the failed resume", name)
+	}
+	if _, err := os.Stat(name + ".part"); !os.IsNotExist(err) {
+		t.Errorf("the .part is kept after a mismatched resume, the next attempt would splice again")
+	}
+}
+
+func TestDownloadResumes(t *testing.T) {
+	content := []byte("0123456789")
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var ranged string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranged = r.Header.Get("Range")
+		http.ServeContent(w, r, "file", modified, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name+".part", content[:4], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".part.validator", []byte(modified.UTC().Format(http.TimeFormat)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GMSFS.Download(server.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %q, want %q", got, content)
+	}
+	if ranged != "bytes=4-" {
+		t.Errorf("Range %q sent, want the download resumed at byte 4", ranged)
+	}
+}
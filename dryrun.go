@@ -0,0 +1,63 @@
+package GMSFS
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDryRun is returned by calls that would hand out a writable file while
+// dry-run mode is on
+var ErrDryRun = errors.New("dry run, file not opened for writing")
+
+// PlannedOp is a mutating call recorded instead of performed in dry-run mode
+type PlannedOp struct {
+	Op      string `json:"op"`
+	Path    string `json:"path"`
+	NewPath string `json:"new_path,omitempty"` // Destination of renames and copies
+}
+
+var (
+	dryRunMutex sync.Mutex
+	dryRun      bool
+	dryRunPlan  []PlannedOp
+)
+
+// SetDryRun turns dry-run mode on or off. While on, mutating calls leave the
+// disk and the cache alone and only record what they would have done.
+func SetDryRun(enabled bool) {
+	dryRunMutex.Lock()
+	dryRun = enabled
+	dryRunMutex.Unlock()
+}
+
+// DryRunPlan returns the operations recorded so far and starts a new plan
+func DryRunPlan() []PlannedOp {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+
+	plan := dryRunPlan
+	dryRunPlan = nil
+	return plan
+}
+
+func dryRunning() bool {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	return dryRun
+}
+
+// planned records op and reports true when dry-run mode is on
+func planned(op string, name string, newName string) bool {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if !dryRun {
+		return false
+	}
+
+	entry := PlannedOp{Op: op, Path: cleanPath(name)}
+	if newName != "" {
+		entry.NewPath = cleanPath(newName)
+	}
+	dryRunPlan = append(dryRunPlan, entry)
+	return true
+}
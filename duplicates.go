@@ -0,0 +1,141 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FindDuplicates returns groups of files below root with identical content.
+// Files are grouped by size first, so only candidates with a size twin are
+// hashed, and the hashes come from the cache where available. Paths that are
+// already hardlinks of each other count as one file. Each group is sorted and
+// holds at least two paths.
+func FindDuplicates(root string) ([][]string, error) {
+	files, err := Find(root, FindOfType(FindFile))
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := map[int64][]string{}
+	for _, info := range files {
+		if info.Size == 0 {
+			continue
+		}
+		bySize[info.Size] = append(bySize[info.Size], info.Name)
+	}
+
+	var groups [][]string
+	for _, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+
+		byHash := map[string][]string{}
+		for _, name := range candidates {
+			sum, err := FileSHA256(name)
+			if err != nil {
+				continue // vanished or unreadable since the listing
+			}
+			byHash[sum] = append(byHash[sum], name)
+		}
+
+		for _, names := range byHash {
+			names = distinctFiles(names)
+			if len(names) < 2 {
+				continue
+			}
+			sort.Strings(names)
+			groups = append(groups, names)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups, nil
+}
+
+// distinctFiles drops every path that is a hardlink of an earlier one
+func distinctFiles(names []string) []string {
+	var stats []os.FileInfo
+	var distinct []string
+	for _, name := range names {
+		stat, err := os.Stat(longPath(name))
+		if err != nil {
+			continue
+		}
+
+		linked := false
+		for _, seen := range stats {
+			if os.SameFile(seen, stat) {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			stats = append(stats, stat)
+			distinct = append(distinct, name)
+		}
+	}
+	return distinct
+}
+
+// HardlinkDuplicates replaces every file of each group, except the first, with
+// a hardlink to the first one and returns the number of bytes freed. Groups
+// are usually the result of FindDuplicates; contents are compared again
+// before linking, a file that changed in between is left alone.
+func HardlinkDuplicates(groups [][]string) (int64, error) {
+	var freed int64
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		keep := group[0]
+		sum, err := sha256File(keep)
+		if err != nil {
+			errorPrinter("HardlinkDuplicates (sha256File): "+err.Error(), keep)
+			return freed, err
+		}
+		keepStat, err := os.Stat(longPath(keep))
+		if err != nil {
+			return freed, err
+		}
+
+		for _, name := range group[1:] {
+			stat, err := os.Stat(longPath(name))
+			if err != nil || os.SameFile(keepStat, stat) {
+				continue
+			}
+			if other, err := sha256File(name); err != nil || other != sum {
+				continue
+			}
+			if planned("link", keep, name) {
+				continue
+			}
+
+			// Link next to the duplicate and rename over it, so name never
+			// disappears if linking fails halfway
+			tmp := filepath.Join(filepath.Dir(name), ".gmsfs-link-"+filepath.Base(name))
+			ioWait()
+			err = os.Link(longPath(keep), longPath(tmp))
+			if err == nil {
+				err = os.Rename(longPath(tmp), longPath(name))
+				if err != nil {
+					os.Remove(longPath(tmp))
+				}
+			}
+			ioDone()
+			if err != nil {
+				errorPrinter("HardlinkDuplicates (os.Link): "+err.Error(), name)
+				return freed, err
+			}
+
+			CacheDelete(cacheKey(name))
+			publishInvalidation(name)
+			audit("link", keep, name, -stat.Size())
+			freed += stat.Size()
+		}
+	}
+
+	return freed, nil
+}
@@ -0,0 +1,316 @@
+package GMSFS
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// KeyProvider hands out the AES keys (16 or 32 bytes) of the encrypted file
+// helpers. Files record the ID of the key they were written with, so keys can
+// be rotated while older files stay readable.
+type KeyProvider interface {
+	// CurrentKey returns the key new files are encrypted with
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with id
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey is a KeyProvider with a single key
+type StaticKey struct {
+	ID    string
+	Bytes []byte
+}
+
+func (sk StaticKey) CurrentKey() (string, []byte, error) { return sk.ID, sk.Bytes, nil }
+
+func (sk StaticKey) Key(id string) ([]byte, error) {
+	if id != sk.ID {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	return sk.Bytes, nil
+}
+
+var (
+	ErrNoKeyProvider = errors.New("no key provider set, see SetKeyProvider")
+	ErrNotEncrypted  = errors.New("not a GMSFS encrypted file")
+	ErrDecrypt       = errors.New("encrypted file is corrupt, truncated or was written with another key")
+)
+
+var (
+	keyMutex    sync.RWMutex
+	keyProvider KeyProvider
+)
+
+// SetKeyProvider sets the keys used by the *Encrypted functions
+func SetKeyProvider(p KeyProvider) {
+	keyMutex.Lock()
+	keyProvider = p
+	keyMutex.Unlock()
+}
+
+func currentKeyProvider() (KeyProvider, error) {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+	if keyProvider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	return keyProvider, nil
+}
+
+// Encrypted files start with encryptedMagic, the key ID (length prefixed) and
+// a random nonce prefix, followed by AES-GCM sealed chunks of up to
+// encryptedChunk plaintext bytes. The nonce of a chunk is the prefix, the
+// chunk counter and a flag marking the last chunk, so reordered, dropped or
+// truncated chunks fail to open.
+const (
+	encryptedMagic  = "GMSFSEC1"
+	encryptedChunk  = 64 * 1024
+	noncePrefixSize = 7
+)
+
+func encryptedNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedWriter encrypts everything written to it into a file created by
+// CreateEncrypted. Close must be called, it writes the final chunk.
+type EncryptedWriter struct {
+	file    *CachedFile
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// CreateEncrypted creates or truncates name and returns a writer that
+// encrypts with the current key of the key provider
+func CreateEncrypted(name string, perm os.FileMode) (*EncryptedWriter, error) {
+	keys, err := currentKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("key id %q is too long", id)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	f, err := Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header := append([]byte(encryptedMagic), byte(len(id)))
+	header = append(header, id...)
+	header = append(header, prefix...)
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &EncryptedWriter{file: f, aead: aead, prefix: prefix, buf: make([]byte, 0, encryptedChunk)}, nil
+}
+
+func (ew *EncryptedWriter) seal(last bool) error {
+	sealed := ew.aead.Seal(nil, encryptedNonce(ew.prefix, ew.counter, last), ew.buf, nil)
+	ew.counter++
+	ew.buf = ew.buf[:0]
+	_, err := ew.file.Write(sealed)
+	return err
+}
+
+func (ew *EncryptedWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, os.ErrClosed
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, the last chunk
+		// is sealed by Close
+		if len(ew.buf) == encryptedChunk {
+			if err := ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(ew.buf[len(ew.buf):encryptedChunk], p)
+		ew.buf = ew.buf[:len(ew.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// Close seals the last chunk and closes the file
+func (ew *EncryptedWriter) Close() error {
+	if ew.closed {
+		return os.ErrClosed
+	}
+	ew.closed = true
+
+	err := ew.seal(true)
+	if cerr := ew.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// encryptedReader decrypts a file opened by OpenEncrypted
+type encryptedReader struct {
+	file    *os.File
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	done    bool
+}
+
+// OpenEncrypted opens a file written by CreateEncrypted or
+// WriteFileEncrypted and returns a reader of its plaintext. Tampering shows up
+// as ErrDecrypt from Read.
+func OpenEncrypted(name string) (io.ReadCloser, error) {
+	keys, err := currentKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(f, encryptedChunk+64)
+
+	magic := make([]byte, len(encryptedMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic[:len(encryptedMagic)]) != encryptedMagic {
+		f.Close()
+		return nil, ErrNotEncrypted
+	}
+	rest := make([]byte, int(magic[len(encryptedMagic)])+noncePrefixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		f.Close()
+		return nil, ErrNotEncrypted
+	}
+	id, prefix := string(rest[:len(rest)-noncePrefixSize]), rest[len(rest)-noncePrefixSize:]
+
+	key, err := keys.Key(id)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &encryptedReader{file: f, r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (er *encryptedReader) Read(p []byte) (int, error) {
+	for len(er.plain) == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+		if err := er.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, er.plain)
+	er.plain = er.plain[n:]
+	return n, nil
+}
+
+func (er *encryptedReader) next() error {
+	sealed := make([]byte, encryptedChunk+er.aead.Overhead())
+	n, err := io.ReadFull(er.r, sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return ErrDecrypt // The last chunk is missing
+		}
+		return err
+	}
+
+	last := err == io.ErrUnexpectedEOF
+	if !last {
+		if _, err := er.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+
+	plain, err := er.aead.Open(sealed[:0], encryptedNonce(er.prefix, er.counter, last), sealed[:n], nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+	er.counter++
+	er.plain = plain
+	er.done = last
+	return nil
+}
+
+func (er *encryptedReader) Close() error {
+	return er.file.Close()
+}
+
+// WriteFileEncrypted is WriteFile with the content encrypted at rest
+func WriteFileEncrypted(name string, content []byte, perm os.FileMode) error {
+	w, err := CreateEncrypted(name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ReadFileEncrypted reads and decrypts a file written by WriteFileEncrypted
+func ReadFileEncrypted(name string) ([]byte, error) {
+	r, err := OpenEncrypted(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,88 @@
+package GMSFS
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorEvent is one failed call or cache refresh in GMSFS
+type ErrorEvent struct {
+	Time time.Time
+	Op   string // The failed call, UpdateFileInfo / UpdateDirectoryContents, or investigate
+	Path string
+	Err  error
+	// Cache is set when the filesystem changed but the cache could not be
+	// refreshed, so the two may disagree
+	Cache bool
+	// Repaired is set when the suspect cache entries were dropped, so the next
+	// read goes to the filesystem
+	Repaired bool
+}
+
+var (
+	errorFeedMutex       sync.Mutex
+	errorFeedSubscribers = map[chan ErrorEvent]struct{}{}
+	errorFeedCount       int32
+)
+
+// SubscribeErrors returns a feed of the errors of GMSFS calls and cache
+// refreshes in this process, to alert on instead of reading the GMSFS.*.log
+// files. Errors returned to the caller are included, so expected ones like a
+// missing file show up too, and so do stale entries dropped by the check of
+// a logged error (see SetInvestigateOptions). A subscriber that falls more
+// than buffer events behind misses events. cancel ends the subscription and
+// closes the channel.
+func SubscribeErrors(buffer int) (events <-chan ErrorEvent, cancel func()) {
+	ch := make(chan ErrorEvent, buffer)
+
+	errorFeedMutex.Lock()
+	errorFeedSubscribers[ch] = struct{}{}
+	atomic.StoreInt32(&errorFeedCount, int32(len(errorFeedSubscribers)))
+	errorFeedMutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			errorFeedMutex.Lock()
+			delete(errorFeedSubscribers, ch)
+			atomic.StoreInt32(&errorFeedCount, int32(len(errorFeedSubscribers)))
+			errorFeedMutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func errorFeedActive() bool {
+	return atomic.LoadInt32(&errorFeedCount) > 0
+}
+
+func publishError(event ErrorEvent) {
+	if !errorFeedActive() {
+		return
+	}
+	event.Time = time.Now()
+
+	errorFeedMutex.Lock()
+	defer errorFeedMutex.Unlock()
+	for ch := range errorFeedSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishOpError reports the error a call returns. Cache errors were
+// reported where they happened, and a dry run is not a failure.
+func publishOpError(op string, path string, err error) {
+	if err == nil || !errorFeedActive() || errors.Is(err, ErrDryRun) {
+		return
+	}
+	var cerr *CacheError
+	if errors.As(err, &cerr) {
+		return
+	}
+	publishError(ErrorEvent{Op: op, Path: path, Err: err})
+}
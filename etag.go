@@ -0,0 +1,40 @@
+package GMSFS
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ETag returns an entity tag for name from the cache only. With a cached
+// SHA256 it is a strong tag of the checksum, the same HTTPHandler sends;
+// otherwise a weak tag of size and modification time.
+func ETag(name string) (string, error) {
+	info, err := Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir {
+		return "", fmt.Errorf("%s is a directory", name)
+	}
+
+	return etagOf(info), nil
+}
+
+func etagOf(info FileInfo) string {
+	if info.SHA256 != "" {
+		return `"` + info.SHA256 + `"`
+	}
+	return `W/"` + strconv.FormatInt(info.Size, 16) + "-" + strconv.FormatInt(info.LastModified.UnixNano(), 16) + `"`
+}
+
+// CheckConditional reports whether a request for name with the given
+// If-None-Match and If-Modified-Since header values can be answered with 304
+// Not Modified. Both come from the cache, empty headers are ignored.
+func CheckConditional(name string, ifNoneMatch string, ifModifiedSince string) (bool, error) {
+	info, err := Stat(name)
+	if err != nil {
+		return false, err
+	}
+
+	return conditionalNotModified(ifNoneMatch, ifModifiedSince, etagOf(info), info.LastModified), nil
+}
@@ -0,0 +1,86 @@
+package GMSFS
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var exchangeMutex sync.Mutex
+
+// Exchange swaps a and b, files or directories, e.g. a staging tree with the
+// live one. On Linux it is a single atomic renameat2(RENAME_EXCHANGE), readers
+// see either the old or the new content and never a missing path. Elsewhere,
+// or when the filesystem does not support it, it falls back to three renames
+// through a temporary name serialized by a lock, which is only atomic between
+// Exchange calls of this process. The cached subtrees of both are dropped.
+func Exchange(a string, b string) (err error) {
+	defer startOp("Exchange", a).end(&err)
+	if err := injectedFault("Exchange", a); err != nil {
+		return err
+	}
+	if err := injectedFault("Exchange", b); err != nil {
+		return err
+	}
+	if planned("exchange", a, b) {
+		return nil
+	}
+	a = cleanPath(a)
+	b = cleanPath(b)
+
+	ioWait()
+	err = exchangeAtomic(a, b)
+	ioDone()
+	if err == ErrNotSupported {
+		err = exchangeRenames(a, b)
+	}
+	if err != nil {
+		errorPrinter("Exchange: "+err.Error(), a)
+		errorPrinter("Exchange: "+err.Error(), b)
+		return err
+	}
+
+	cacheErr := exchanged(a, b)
+	audit("exchange", a, b, 0)
+	return cacheErr
+}
+
+// exchangeRenames swaps a and b with a -> tmp, b -> a, tmp -> b, undoing the
+// completed steps when one fails
+func exchangeRenames(a string, b string) error {
+	exchangeMutex.Lock()
+	defer exchangeMutex.Unlock()
+
+	r := make([]byte, 8)
+	rand.Read(r)
+	tmp := filepath.Join(filepath.Dir(a), ".gmsfs-exchange-"+hex.EncodeToString(r))
+
+	ioWait()
+	defer ioDone()
+
+	if err := os.Rename(longPath(a), longPath(tmp)); err != nil {
+		return err
+	}
+	if err := os.Rename(longPath(b), longPath(a)); err != nil {
+		os.Rename(longPath(tmp), longPath(a))
+		return err
+	}
+	if err := os.Rename(longPath(tmp), longPath(b)); err != nil {
+		os.Rename(longPath(a), longPath(b))
+		os.Rename(longPath(tmp), longPath(a))
+		return err
+	}
+	return nil
+}
+
+// exchanged updates the cache after a and b were swapped on disk
+func exchanged(a string, b string) error {
+	CacheInvalidatePrefix(a)
+	CacheInvalidatePrefix(b)
+	err := firstError(UpdateDirectoryContents(filepath.Dir(a)), UpdateDirectoryContents(filepath.Dir(b)))
+	publishTreeInvalidation(a)
+	publishTreeInvalidation(b)
+	return err
+}
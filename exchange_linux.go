@@ -0,0 +1,20 @@
+package GMSFS
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// exchangeAtomic swaps a and b with renameat2(RENAME_EXCHANGE), available
+// since Linux 3.15 on most local filesystems
+func exchangeAtomic(a string, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, longPath(a), unix.AT_FDCWD, longPath(b), unix.RENAME_EXCHANGE)
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		return ErrNotSupported
+	}
+	if err != nil {
+		return &os.LinkError{Op: "exchange", Old: a, New: b, Err: err}
+	}
+	return nil
+}
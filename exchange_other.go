@@ -0,0 +1,9 @@
+//go:build !linux
+
+package GMSFS
+
+// exchangeAtomic has no equivalent of RENAME_EXCHANGE outside Linux, Exchange
+// falls back to renames there
+func exchangeAtomic(a string, b string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,96 @@
+package GMSFS
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fault makes calls of op on paths matching pattern fail or slow down
+type fault struct {
+	op      string
+	pattern string
+	err     error
+	latency time.Duration
+}
+
+var (
+	faultMutex  sync.RWMutex
+	faults      []fault
+	faultsArmed int32
+)
+
+// InjectError makes op (a function name such as "WriteFile", or "*" for
+// every operation) fail with err for paths matching pattern. pattern is a
+// filepath.Match glob that also matches everything below a matching
+// directory, so "/data/*" covers the whole tree. Meant for testing error
+// handling, e.g. InjectError("WriteFile", "/data/*", syscall.ENOSPC).
+func InjectError(op string, pattern string, err error) {
+	addFault(fault{op: op, pattern: cleanPath(pattern), err: err})
+}
+
+// InjectLatency delays op for paths matching pattern by latency
+func InjectLatency(op string, pattern string, latency time.Duration) {
+	addFault(fault{op: op, pattern: cleanPath(pattern), latency: latency})
+}
+
+// ClearFaults removes all injected errors and latencies
+func ClearFaults() {
+	faultMutex.Lock()
+	faults = nil
+	atomic.StoreInt32(&faultsArmed, 0)
+	faultMutex.Unlock()
+}
+
+func addFault(f fault) {
+	faultMutex.Lock()
+	faults = append(faults, f)
+	atomic.StoreInt32(&faultsArmed, 1)
+	faultMutex.Unlock()
+}
+
+// injectedFault applies the faults configured for op on name
+func injectedFault(op string, name string) error {
+	if atomic.LoadInt32(&faultsArmed) == 0 {
+		return nil
+	}
+
+	name = cleanPath(name)
+	var latency time.Duration
+	var err error
+
+	faultMutex.RLock()
+	for _, f := range faults {
+		if (f.op != op && f.op != "*") || !faultMatches(f.pattern, name) {
+			continue
+		}
+		latency += f.latency
+		if err == nil && f.err != nil {
+			err = f.err
+		}
+	}
+	faultMutex.RUnlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+func faultMatches(pattern string, name string) bool {
+	for {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		parent := filepath.Dir(name)
+		if parent == name {
+			return false
+		}
+		name = parent
+	}
+}
@@ -0,0 +1,75 @@
+package GMSFS_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// Injected errors, errNoSpace and EIO are not defined on every platform
+var (
+	errNoSpace = errors.New("no space left on device")
+	errIO      = errors.New("input/output error")
+)
+
+func TestInjectError(t *testing.T) {
+	defer GMSFS.ClearFaults()
+	dir := t.TempDir()
+	full := filepath.Join(dir, "full")
+	if err := os.Mkdir(full, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	GMSFS.InjectError("WriteFile", filepath.Join(full, "*"), errNoSpace)
+
+	name := filepath.Join(full, "file")
+	if err := GMSFS.WriteFile(name, []byte("x"), 0644); !errors.Is(err, errNoSpace) {
+		t.Fatalf("WriteFile = %v, want ENOSPC", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("the failed WriteFile created the file: %v", err)
+	}
+	// Other paths and other operations are not affected
+	if err := GMSFS.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0644); err != nil {
+		t.Errorf("WriteFile outside the pattern = %v", err)
+	}
+	if _, err := GMSFS.Stat(full); err != nil {
+		t.Errorf("Stat = %v", err)
+	}
+
+	GMSFS.ClearFaults()
+	if err := GMSFS.WriteFile(name, []byte("x"), 0644); err != nil {
+		t.Errorf("WriteFile after ClearFaults = %v", err)
+	}
+}
+
+func TestInjectErrorAnyOperation(t *testing.T) {
+	defer GMSFS.ClearFaults()
+	dir := t.TempDir()
+
+	GMSFS.InjectError("*", dir, errIO)
+	if _, err := GMSFS.Stat(filepath.Join(dir, "x")); !errors.Is(err, errIO) {
+		t.Errorf("Stat below the pattern = %v, want EIO", err)
+	}
+	if _, err := GMSFS.ReadDir(dir); !errors.Is(err, errIO) {
+		t.Errorf("ReadDir = %v, want EIO", err)
+	}
+}
+
+func TestInjectLatency(t *testing.T) {
+	defer GMSFS.ClearFaults()
+	dir := t.TempDir()
+
+	GMSFS.InjectLatency("Stat", dir, 50*time.Millisecond)
+	start := time.Now()
+	if _, err := GMSFS.Stat(dir); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Stat took %v, want at least the injected 50ms", elapsed)
+	}
+}
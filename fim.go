@@ -0,0 +1,244 @@
+package GMSFS
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FIM (file integrity monitor) baselines a tree and reports files that were
+// added, removed or modified by anything other than GMSFS itself. Changes
+// made through GMSFS calls are folded into the baseline silently. Changes are
+// reported as ManifestMismatch, with ManifestExtra for added files and
+// ManifestMissing for removed ones.
+//
+// Files are only re-hashed when their size or modification time changed,
+// unless Rehash is set, which catches content changes with a restored mtime
+// at the cost of reading the whole tree on every check.
+type FIM struct {
+	Root   string
+	Rehash bool
+
+	mutex    sync.Mutex
+	baseline map[string]ManifestEntry
+	own      map[string]bool // cache keys changed through GMSFS since the last check
+}
+
+var (
+	fimMutex    sync.RWMutex
+	fimMonitors = map[*FIM]bool{}
+)
+
+// NewFIM baselines root from disk. Call Close when the monitor is no longer
+// needed.
+func NewFIM(root string) (*FIM, error) {
+	m := &FIM{Root: cleanPath(root), own: map[string]bool{}}
+
+	current, err := m.scan(nil)
+	if err != nil {
+		errorPrinter("NewFIM (scan): "+err.Error(), root)
+		return nil, err
+	}
+	m.baseline = current
+
+	m.register()
+	return m, nil
+}
+
+// NewFIMFromManifest uses a manifest written by WriteManifest as the baseline,
+// so changes made while no monitor was running show up on the first Check.
+func NewFIMFromManifest(root string, manifestPath string) (*FIM, error) {
+	entries, err := ReadManifest(manifestPath)
+	if err != nil {
+		errorPrinter("NewFIMFromManifest (ReadManifest): "+err.Error(), manifestPath)
+		return nil, err
+	}
+
+	m := &FIM{Root: cleanPath(root), own: map[string]bool{}, baseline: map[string]ManifestEntry{}}
+	for _, entry := range entries {
+		m.baseline[entry.Path] = entry
+	}
+
+	m.register()
+	return m, nil
+}
+
+func (m *FIM) register() {
+	fimMutex.Lock()
+	fimMonitors[m] = true
+	fimMutex.Unlock()
+}
+
+// Close stops m from tracking GMSFS's own changes
+func (m *FIM) Close() {
+	fimMutex.Lock()
+	delete(fimMonitors, m)
+	fimMutex.Unlock()
+}
+
+// Baseline returns the current baseline sorted by path
+func (m *FIM) Baseline() []ManifestEntry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entries := make([]ManifestEntry, 0, len(m.baseline))
+	for _, entry := range m.baseline {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Check rescans the tree, returns the changes made outside of GMSFS since the
+// baseline or the previous Check, and moves the baseline to the current state.
+func (m *FIM) Check() ([]ManifestMismatch, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Take the own changes before scanning, a GMSFS write racing with the
+	// scan is then reported by the next Check at the latest
+	fimMutex.Lock()
+	own := m.own
+	m.own = map[string]bool{}
+	fimMutex.Unlock()
+
+	current, err := m.scan(m.baseline)
+	if err != nil {
+		errorPrinter("FIM.Check (scan): "+err.Error(), m.Root)
+		return nil, err
+	}
+
+	var expected, actual []ManifestEntry
+	for rel, entry := range m.baseline {
+		if !m.ownChange(own, rel) {
+			expected = append(expected, entry)
+		}
+	}
+	for rel, entry := range current {
+		if !m.ownChange(own, rel) {
+			actual = append(actual, entry)
+		}
+	}
+	m.baseline = current
+
+	return compareManifests(expected, actual), nil
+}
+
+// Run calls Check every interval and hands each change to handler until ctx
+// is done
+func (m *FIM) Run(ctx context.Context, interval time.Duration, handler func(ManifestMismatch)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changes, err := m.Check()
+		if err != nil {
+			continue
+		}
+		for _, change := range changes {
+			handler(change)
+		}
+	}
+}
+
+// Events works like Run but delivers the changes on a channel, which is
+// closed when ctx is done
+func (m *FIM) Events(ctx context.Context, interval time.Duration) <-chan ManifestMismatch {
+	events := make(chan ManifestMismatch)
+	go func() {
+		defer close(events)
+		m.Run(ctx, interval, func(change ManifestMismatch) {
+			select {
+			case events <- change:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return events
+}
+
+func (m *FIM) ownChange(own map[string]bool, rel string) bool {
+	key := cacheKey(filepath.Join(m.Root, filepath.FromSlash(rel)))
+	for changed := range own {
+		if keyWithin(key, changed) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan walks the tree on disk, reusing the hashes of previous for files whose
+// size and modification time did not change
+func (m *FIM) scan(previous map[string]ManifestEntry) (map[string]ManifestEntry, error) {
+	current := map[string]ManifestEntry{}
+	err := filepath.WalkDir(m.Root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == m.Root {
+				return err
+			}
+			errorPrinter("FIM (WalkDir): "+err.Error(), name)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		stat, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		rel, err := filepath.Rel(m.Root, name)
+		if err != nil {
+			return err
+		}
+		entry := ManifestEntry{Path: filepath.ToSlash(rel), Size: stat.Size(), ModTime: stat.ModTime()}
+
+		if old, ok := previous[entry.Path]; ok && !m.Rehash && old.Size == entry.Size && old.ModTime.Equal(entry.ModTime) {
+			entry.SHA256 = old.SHA256
+		} else if entry.SHA256, err = sha256File(name); err != nil {
+			return nil // removed or unreadable, reported as missing
+		}
+
+		current[entry.Path] = entry
+		return nil
+	})
+
+	return current, err
+}
+
+// fimOwnChange tells the monitors whose tree holds name, or lies below it,
+// that name (and anything below it) was changed through GMSFS. Other names
+// are not kept: a monitor that is never checked would collect every write of
+// the process.
+func fimOwnChange(name string) {
+	fimMutex.Lock()
+	defer fimMutex.Unlock()
+	if len(fimMonitors) == 0 {
+		return
+	}
+
+	key := cacheKey(name)
+	for m := range fimMonitors {
+		root := cacheKey(m.Root)
+		if keyWithin(key, root) || keyWithin(root, key) {
+			m.own[key] = true
+		}
+	}
+}
+
+// keyWithin reports whether the cache key is dir or below it
+func keyWithin(key string, dir string) bool {
+	return key == dir || strings.HasPrefix(key, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+}
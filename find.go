@@ -0,0 +1,137 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FindType selects the kind of entry Find returns
+type FindType int
+
+const (
+	FindFile FindType = iota + 1
+	FindDir
+	FindSymlink
+)
+
+type findOptions struct {
+	maxDepth   int
+	predicates []func(FileInfo) bool
+}
+
+// FindOption narrows the entries returned by Find. All options must match.
+type FindOption func(*findOptions)
+
+// FindName matches the base name against a glob, case insensitive
+func FindName(pattern string) FindOption {
+	pattern = strings.ToLower(pattern)
+	return findPredicate(func(info FileInfo) bool {
+		matched, _ := filepath.Match(pattern, strings.ToLower(filepath.Base(info.Name)))
+		return matched
+	})
+}
+
+// FindRegexp matches the base name against re
+func FindRegexp(re *regexp.Regexp) FindOption {
+	return findPredicate(func(info FileInfo) bool { return re.MatchString(filepath.Base(info.Name)) })
+}
+
+func FindMinSize(size int64) FindOption {
+	return findPredicate(func(info FileInfo) bool { return info.Size >= size })
+}
+
+func FindMaxSize(size int64) FindOption {
+	return findPredicate(func(info FileInfo) bool { return info.Size <= size })
+}
+
+func FindModifiedAfter(t time.Time) FindOption {
+	return findPredicate(func(info FileInfo) bool { return info.LastModified.After(t) })
+}
+
+func FindModifiedBefore(t time.Time) FindOption {
+	return findPredicate(func(info FileInfo) bool { return info.LastModified.Before(t) })
+}
+
+func FindOfType(t FindType) FindOption {
+	return findPredicate(func(info FileInfo) bool {
+		switch t {
+		case FindFile:
+			return !info.IsDir && info.Mode&os.ModeSymlink == 0
+		case FindDir:
+			return info.IsDir
+		case FindSymlink:
+			return info.Mode&os.ModeSymlink != 0
+		}
+		return false
+	})
+}
+
+// FindMaxDepth stops descending below depth, the entries of root are depth 1
+func FindMaxDepth(depth int) FindOption {
+	return func(o *findOptions) { o.maxDepth = depth }
+}
+
+// FindFilter adds a custom predicate, info.Name holds the path of the entry
+func FindFilter(fn func(info FileInfo) bool) FindOption {
+	return findPredicate(fn)
+}
+
+func findPredicate(fn func(FileInfo) bool) FindOption {
+	return func(o *findOptions) { o.predicates = append(o.predicates, fn) }
+}
+
+// Find walks root over cached directory listings and returns the entries
+// matching every option. Name of the returned entries is the path joined to
+// root. Symlinked directories are not followed.
+func Find(root string, opts ...FindOption) ([]FileInfo, error) {
+	options := findOptions{maxDepth: -1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	root = cleanPath(root)
+	contents, err := ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []FileInfo
+	var walk func(dir string, contents []FileInfo, depth int)
+	walk = func(dir string, contents []FileInfo, depth int) {
+		for _, entry := range contents {
+			entry.Name = filepath.Join(dir, entry.Name)
+			entry.Contents = nil
+			if options.match(entry) {
+				found = append(found, entry)
+			}
+
+			if !entry.IsDir || entry.Mode&os.ModeSymlink != 0 {
+				continue
+			}
+			if options.maxDepth >= 0 && depth >= options.maxDepth {
+				continue
+			}
+			sub, err := ReadDir(entry.Name)
+			if err != nil {
+				errorPrinter("Find (ReadDir): "+err.Error(), entry.Name)
+				continue
+			}
+			walk(entry.Name, sub, depth+1)
+		}
+	}
+	walk(root, contents, 1)
+
+	return found, nil
+}
+
+func (o *findOptions) match(info FileInfo) bool {
+	for _, predicate := range o.predicates {
+		if !predicate(info) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package GMSFS
+
+import "time"
+
+// ModifiedWithin reports whether path was modified less than d ago, by the
+// cached modification time. Pass Strong to revalidate the entry on disk
+// first. A missing path is false.
+func ModifiedWithin(path string, d time.Duration, level ...Consistency) bool {
+	modified, ok := lastModified(path, level)
+	return ok && clockSince(modified) < d
+}
+
+// IsOlderThan reports whether path was last modified more than d ago, by
+// the cached modification time. Pass Strong to revalidate the entry on disk
+// first. A missing path is false, it is not old, it is gone.
+func IsOlderThan(path string, d time.Duration, level ...Consistency) bool {
+	modified, ok := lastModified(path, level)
+	return ok && clockSince(modified) > d
+}
+
+func lastModified(path string, level []Consistency) (time.Time, bool) {
+	stat := Stat
+	if len(level) > 0 && level[0] == Strong {
+		stat = StatStrong
+	}
+	info, err := stat(path)
+	if err != nil || !info.Exists {
+		return time.Time{}, false
+	}
+	return info.LastModified, true
+}
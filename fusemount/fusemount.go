@@ -0,0 +1,265 @@
+//go:build linux || darwin
+
+// Package fusemount exports a GMSFS Backend through FUSE, so tools that are
+// not written in Go see the same view of the data while GMSFS keeps the
+// metadata cache.
+package fusemount
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// Mount exports backend on the directory dir. Call Unmount on the returned
+// server (or Wait for an external umount) to stop serving.
+func Mount(dir string, backend GMSFS.Backend, readOnly bool) (*fuse.Server, error) {
+	root := &node{backend: backend, name: "/", readOnly: readOnly}
+
+	options := &fs.Options{}
+	options.FsName = "gmsfs"
+	options.Name = "gmsfs"
+	if readOnly {
+		options.Options = append(options.Options, "ro")
+	}
+
+	return fs.Mount(dir, root, options)
+}
+
+type node struct {
+	fs.Inode
+	backend  GMSFS.Backend
+	name     string
+	readOnly bool
+}
+
+var _ = (fs.NodeGetattrer)((*node)(nil))
+var _ = (fs.NodeLookuper)((*node)(nil))
+var _ = (fs.NodeReaddirer)((*node)(nil))
+var _ = (fs.NodeOpener)((*node)(nil))
+var _ = (fs.NodeCreater)((*node)(nil))
+var _ = (fs.NodeUnlinker)((*node)(nil))
+var _ = (fs.NodeSetattrer)((*node)(nil))
+
+func fillAttr(info GMSFS.FileInfo, out *fuse.Attr) {
+	out.Mode = uint32(info.Mode.Perm())
+	if info.IsDir {
+		out.Mode |= syscall.S_IFDIR
+	} else {
+		out.Mode |= syscall.S_IFREG
+	}
+	out.Size = uint64(info.Size)
+	mtime := info.LastModified
+	out.SetTimes(nil, &mtime, &mtime)
+}
+
+func (n *node) child(name string) *node {
+	return &node{backend: n.backend, name: path.Join(n.name, name), readOnly: n.readOnly}
+}
+
+func (n *node) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if h, ok := f.(*handle); ok {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		out.Mode = syscall.S_IFREG | uint32(h.perm)
+		out.Size = uint64(len(h.data))
+		return 0
+	}
+
+	info, err := n.backend.Stat(n.name)
+	if err != nil {
+		return fs.ToErrno(err)
+	}
+	fillAttr(info, &out.Attr)
+
+	return 0
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	child := n.child(name)
+	info, err := n.backend.Stat(child.name)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+	fillAttr(info, &out.Attr)
+
+	mode := uint32(syscall.S_IFREG)
+	if info.IsDir {
+		mode = syscall.S_IFDIR
+	}
+
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: mode}), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	contents, err := n.backend.ReadDir(n.name)
+	if err != nil {
+		return nil, fs.ToErrno(err)
+	}
+
+	entries := make([]fuse.DirEntry, 0, len(contents))
+	for _, entry := range contents {
+		mode := uint32(syscall.S_IFREG)
+		if entry.IsDir {
+			mode = syscall.S_IFDIR
+		}
+		entries = append(entries, fuse.DirEntry{Name: entry.Name, Mode: mode})
+	}
+
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	writable := flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	if writable && n.readOnly {
+		return nil, 0, syscall.EROFS
+	}
+
+	h := &handle{node: n, perm: 0644}
+	if info, err := n.backend.Stat(n.name); err == nil {
+		h.perm = info.Mode.Perm()
+	}
+
+	if flags&syscall.O_TRUNC != 0 {
+		h.dirty = true
+	} else {
+		data, err := n.backend.ReadFile(n.name)
+		if err != nil {
+			return nil, 0, fs.ToErrno(err)
+		}
+		h.data = data
+	}
+
+	return h, 0, 0
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if n.readOnly {
+		return nil, nil, 0, syscall.EROFS
+	}
+
+	child := n.child(name)
+	perm := mode & 0777
+	if err := n.backend.WriteFile(child.name, nil, os.FileMode(perm)); err != nil {
+		return nil, nil, 0, fs.ToErrno(err)
+	}
+	out.Mode = syscall.S_IFREG | perm
+
+	inode := n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFREG})
+	return inode, &handle{node: child, perm: os.FileMode(perm)}, 0, 0
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	if n.readOnly {
+		return syscall.EROFS
+	}
+
+	return fs.ToErrno(n.backend.Remove(n.child(name).name))
+}
+
+func (n *node) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	size, ok := in.GetSize()
+	if !ok {
+		return n.Getattr(ctx, f, out)
+	}
+	if n.readOnly {
+		return syscall.EROFS
+	}
+
+	// Truncation outside of an open handle rewrites the whole object
+	h, isHandle := f.(*handle)
+	if !isHandle {
+		data, err := n.backend.ReadFile(n.name)
+		if err != nil {
+			return fs.ToErrno(err)
+		}
+		h = &handle{node: n, data: data, perm: 0644}
+	}
+
+	h.mu.Lock()
+	h.data = resize(h.data, int(size))
+	h.dirty = true
+	h.mu.Unlock()
+
+	if !isHandle {
+		if errno := h.Flush(ctx); errno != 0 {
+			return errno
+		}
+	}
+
+	return n.Getattr(ctx, f, out)
+}
+
+// handle buffers the whole file, as the Backend interface only offers whole
+// file reads and writes. Dirty buffers are written back on flush.
+type handle struct {
+	mu    sync.Mutex
+	node  *node
+	data  []byte
+	perm  os.FileMode
+	dirty bool
+}
+
+var _ = (fs.FileReader)((*handle)(nil))
+var _ = (fs.FileWriter)((*handle)(nil))
+var _ = (fs.FileFlusher)((*handle)(nil))
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if off >= int64(len(h.data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := off + int64(len(dest))
+	if end > int64(len(h.data)) {
+		end = int64(len(h.data))
+	}
+
+	return fuse.ReadResultData(append([]byte{}, h.data[off:end]...)), 0
+}
+
+func (h *handle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.node.readOnly {
+		return 0, syscall.EROFS
+	}
+	if end := int(off) + len(data); end > len(h.data) {
+		h.data = resize(h.data, end)
+	}
+	copy(h.data[off:], data)
+	h.dirty = true
+
+	return uint32(len(data)), 0
+}
+
+func (h *handle) Flush(ctx context.Context) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.dirty {
+		return 0
+	}
+	if err := h.node.backend.WriteFile(h.node.name, h.data, h.perm); err != nil {
+		return fs.ToErrno(err)
+	}
+	h.dirty = false
+
+	return 0
+}
+
+func resize(data []byte, size int) []byte {
+	if size <= len(data) {
+		return data[:size]
+	}
+	return append(data, make([]byte, size-len(data))...)
+}
@@ -0,0 +1,96 @@
+package GMSFS
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// cacheGeneration numbers every store into the cache, see CacheItem.Generation
+var cacheGeneration uint64
+
+// globCache holds Glob results together with the generation of the directory
+// entry they were matched against. Cost is the number of matched names.
+var globCache, _ = ristretto.NewCache[string, globResult](&ristretto.Config[string, globResult]{
+	NumCounters: 1e5,
+	MaxCost:     1 << 20,
+	BufferItems: 64,
+})
+
+type globResult struct {
+	dirKey     string
+	generation uint64
+	matches    []string
+}
+
+// globDir returns the directory a pattern is matched in, if the pattern only
+// has meta characters in its last element
+func globDir(pattern string) (string, bool) {
+	dir := filepath.Dir(pattern)
+	meta := "*?["
+	if runtime.GOOS != "windows" {
+		meta += `\`
+	}
+	return dir, !strings.ContainsAny(dir, meta)
+}
+
+// globGeneration returns the current generation of the directory entry
+// pattern is matched against, reading the directory when it is not cached
+func globGeneration(pattern string) (uint64, bool) {
+	dir, ok := globDir(pattern)
+	if !ok {
+		return 0, false
+	}
+
+	dirKey := cacheKey(dir)
+	item, found := cache.Get(dirKey)
+	if !found {
+		if _, err := ReadDir(dir); err != nil {
+			return 0, false
+		}
+		cache.Wait()
+		if item, found = cache.Get(dirKey); !found {
+			return 0, false
+		}
+	}
+	return item.Generation, true
+}
+
+func globCacheGet(pattern string) ([]string, bool) {
+	result, found := globCache.Get(pattern)
+	if !found {
+		return nil, false
+	}
+
+	// CacheGet drops the directory entry once it expired (or changed on disk,
+	// with SoftCacheTime), the matches are only as fresh as the listing
+	if _, ok := CacheGet(result.dirKey); !ok {
+		globCache.Del(pattern)
+		return nil, false
+	}
+	item, found := cache.Get(result.dirKey)
+	if !found || item.Generation != result.generation {
+		globCache.Del(pattern)
+		return nil, false
+	}
+	return append([]string(nil), result.matches...), true
+}
+
+// globCacheSet stores matches unless the directory changed while they were
+// being matched
+func globCacheSet(pattern string, generation uint64, matches []string) {
+	dir, _ := globDir(pattern)
+	dirKey := cacheKey(dir)
+	if item, found := cache.Get(dirKey); !found || item.Generation != generation {
+		return
+	}
+
+	result := globResult{
+		dirKey:     dirKey,
+		generation: generation,
+		matches:    append([]string(nil), matches...),
+	}
+	globCache.Set(pattern, result, int64(len(matches))+1)
+}
@@ -0,0 +1,190 @@
+// Package gmsfstest checks that a GMSFS.Backend behaves like the local
+// backend, for third-party Backend implementations to run in their own tests:
+//
+//	func TestBackend(t *testing.T) {
+//		gmsfstest.TestBackend(t, newScratchBackend(t))
+//	}
+package gmsfstest
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// Prefix is put in front of the names of the files TestBackend writes
+const Prefix = "gmsfstest-"
+
+// TestBackend writes a few files at the top of backend, checks that Stat,
+// ReadDir, ReadFile, WriteFile and Remove treat them the way LocalBackend
+// does and runs fstest.TestFS over GMSFS.NewFS(backend). The files are
+// removed again at the end. Anything else stored in backend is listed too,
+// so a backend of its own, or one rooted at an empty prefix, keeps the test
+// fast.
+//
+// Permission bits are not compared, object stores do not keep them, and
+// neither is removing a missing name, which S3 reports as a success.
+func TestBackend(t *testing.T, backend GMSFS.Backend) {
+	t.Helper()
+
+	files := map[string][]byte{
+		Prefix + "empty":  {},
+		Prefix + "hello":  []byte("hello, world\n"),
+		Prefix + "binary": bytes.Repeat([]byte{0, 1, 2, 0xff}, 4096),
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t.Cleanup(func() {
+		for _, name := range names {
+			backend.Remove(name)
+		}
+	})
+	for _, name := range names {
+		if err := backend.WriteFile(name, files[name], 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %v", name, err)
+		}
+	}
+
+	t.Run("Stat", func(t *testing.T) {
+		for _, name := range names {
+			info, err := backend.Stat(name)
+			if err != nil {
+				t.Errorf("Stat(%q): %v", name, err)
+				continue
+			}
+			checkInfo(t, "Stat", name, info, int64(len(files[name])))
+		}
+
+		root, err := backend.Stat("")
+		if err != nil {
+			t.Errorf("Stat(\"\"): %v", err)
+		} else if !root.Exists || !root.IsDir {
+			t.Errorf("Stat(\"\") = exists %v, dir %v, want a directory", root.Exists, root.IsDir)
+		}
+	})
+
+	t.Run("ReadFile", func(t *testing.T) {
+		for _, name := range names {
+			content, err := backend.ReadFile(name)
+			if err != nil {
+				t.Errorf("ReadFile(%q): %v", name, err)
+				continue
+			}
+			if !bytes.Equal(content, files[name]) {
+				t.Errorf("ReadFile(%q) = %d bytes, want the %d written", name, len(content), len(files[name]))
+			}
+		}
+	})
+
+	t.Run("ReadDir", func(t *testing.T) {
+		contents, err := backend.ReadDir("")
+		if err != nil {
+			t.Fatalf("ReadDir(\"\"): %v", err)
+		}
+		listed := make(map[string]GMSFS.FileInfo, len(contents))
+		for _, entry := range contents {
+			if _, ok := listed[entry.Name]; ok {
+				t.Errorf("ReadDir(\"\") lists %q twice", entry.Name)
+			}
+			listed[entry.Name] = entry
+		}
+		for _, name := range names {
+			entry, ok := listed[name]
+			if !ok {
+				t.Errorf("ReadDir(\"\") does not list %q", name)
+				continue
+			}
+			checkInfo(t, "ReadDir", name, entry, int64(len(files[name])))
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		name := Prefix + "hello"
+		content := []byte("bye")
+		if err := backend.WriteFile(name, content, 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %v", name, err)
+		}
+		files[name] = content
+
+		read, err := backend.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%q): %v", name, err)
+		}
+		if !bytes.Equal(read, content) {
+			t.Errorf("ReadFile(%q) after overwrite = %q, want %q", name, read, content)
+		}
+		info, err := backend.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q): %v", name, err)
+		}
+		checkInfo(t, "Stat after overwrite", name, info, int64(len(content)))
+	})
+
+	t.Run("NotExist", func(t *testing.T) {
+		name := Prefix + "missing"
+		if _, err := backend.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+		if _, err := backend.ReadFile(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ReadFile(%q) = %v, want fs.ErrNotExist", name, err)
+		}
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		if err := fstest.TestFS(GMSFS.NewFS(backend), names...); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		name := Prefix + "empty"
+		if err := backend.Remove(name); err != nil {
+			t.Fatalf("Remove(%q): %v", name, err)
+		}
+		if _, err := backend.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%q) after Remove = %v, want fs.ErrNotExist", name, err)
+		}
+		if _, err := backend.ReadFile(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ReadFile(%q) after Remove = %v, want fs.ErrNotExist", name, err)
+		}
+		contents, err := backend.ReadDir("")
+		if err != nil {
+			t.Fatalf("ReadDir(\"\"): %v", err)
+		}
+		for _, entry := range contents {
+			if entry.Name == name {
+				t.Errorf("ReadDir(\"\") still lists %q after Remove", name)
+			}
+		}
+	})
+}
+
+// checkInfo reports the ways info, returned by op for a file of size bytes,
+// differs from what LocalBackend returns
+func checkInfo(t *testing.T, op string, name string, info GMSFS.FileInfo, size int64) {
+	t.Helper()
+	if !info.Exists {
+		t.Errorf("%s(%q): Exists is false", op, name)
+	}
+	if info.IsDir {
+		t.Errorf("%s(%q): IsDir is true for a file", op, name)
+	}
+	if info.Name != name {
+		t.Errorf("%s(%q): Name is %q", op, name, info.Name)
+	}
+	if info.Size != size {
+		t.Errorf("%s(%q): Size is %d, want %d", op, name, info.Size, size)
+	}
+	if info.LastModified.IsZero() {
+		t.Errorf("%s(%q): LastModified is not set", op, name)
+	}
+}
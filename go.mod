@@ -2,4 +2,19 @@ module github.com/inpadi/GMSFS
 
 go 1.19
 
-require github.com/orcaman/concurrent-map/v2 v2.0.1
+require (
+	github.com/go-git/go-billy/v5 v5.5.0
+	github.com/hanwen/go-fuse/v2 v2.5.1
+	github.com/orcaman/concurrent-map/v2 v2.0.1
+	go.opentelemetry.io/otel v1.16.0
+	go.opentelemetry.io/otel/trace v1.16.0
+	golang.org/x/net v0.33.0
+	golang.org/x/sys v0.28.0
+	golang.org/x/text v0.21.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.16.0 // indirect
+)
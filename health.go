@@ -0,0 +1,74 @@
+package GMSFS
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HealthCheckTimeout bounds how long HealthCheck waits for the storage
+var HealthCheckTimeout = 5 * time.Second
+
+// HealthCheck verifies dir by writing, reading back and deleting a small
+// probe file. On a read-only mount it only lists dir. The cached entry and
+// listing of dir are refreshed in both cases. A hanging filesystem makes it
+// fail after HealthCheckTimeout.
+func HealthCheck(dir string) error {
+	dir = cleanPath(dir)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- healthProbe(dir)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(HealthCheckTimeout):
+		return fmt.Errorf("health check of %s timed out after %v", dir, HealthCheckTimeout)
+	}
+}
+
+func healthProbe(dir string) error {
+	b := make([]byte, 8)
+	rand.Read(b)
+	probe := filepath.Join(dir, ".gmsfs-health-"+hex.EncodeToString(b))
+	content := []byte("GMSFS health " + hex.EncodeToString(b))
+
+	ioWait()
+	err := os.WriteFile(longPath(probe), content, 0600)
+	ioDone()
+	if errors.Is(err, errReadOnlyFS) {
+		_, err = ReadDirStrong(dir)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	ioWait()
+	read, err := os.ReadFile(longPath(probe))
+	ioDone()
+	if err == nil && !bytes.Equal(read, content) {
+		err = fmt.Errorf("health check of %s read back different content", dir)
+	}
+
+	ioWait()
+	rerr := os.Remove(longPath(probe))
+	ioDone()
+	if err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return err
+	}
+
+	CacheDelete(cacheKey(probe))
+	_, err = ReadDirStrong(dir)
+	return err
+}
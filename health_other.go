@@ -0,0 +1,7 @@
+//go:build !plan9
+
+package GMSFS
+
+import "syscall"
+
+var errReadOnlyFS error = syscall.EROFS
@@ -0,0 +1,6 @@
+package GMSFS
+
+import "errors"
+
+// Plan 9 has no EROFS, a read-only mount fails the probe like any other error
+var errReadOnlyFS = errors.New("read-only file system")
@@ -0,0 +1,99 @@
+package GMSFS
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HTTPHandler serves the files below root. Last-Modified and ETag are taken
+// from the cache, so conditional requests are answered without touching disk.
+func HTTPHandler(root string) http.Handler {
+	return &httpHandler{root: cleanPath(root)}
+}
+
+type httpHandler struct {
+	root string
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, err := joinRoot(h.root, r.URL.Path)
+	if err != nil {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	info, err := Stat(name)
+	if err == nil && info.IsDir {
+		name = filepath.Join(name, "index.html")
+		info, err = Stat(name)
+	}
+	if err != nil || !info.Exists || info.IsDir {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := ""
+	if sum, err := FileSHA256(name); err == nil {
+		etag = `"` + sum + `"`
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	if contentType, err := ContentType(name); err == nil {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	if notModified(r, etag, info.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		errorPrinter("HTTPHandler (os.Open): "+err.Error(), name)
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	http.ServeContent(w, r, filepath.Base(name), info.LastModified, f)
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since, with the ETag
+// taking precedence as described in RFC 7232
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	return conditionalNotModified(r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since"), etag, modTime)
+}
+
+func conditionalNotModified(ifNoneMatch string, ifModifiedSince string, etag string, modTime time.Time) bool {
+	if ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		// Weak comparison, as for GET and HEAD
+		etag = strings.TrimPrefix(etag, "W/")
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince != "" {
+		t, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !modTime.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,301 @@
+package GMSFS
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HTTPFileSystem returns the files below root as an http.FileSystem, for
+// http.FileServer and middleware stacks that expect one. Stat and Readdir
+// are answered from the cache, file contents are read from disk.
+func HTTPFileSystem(root string) http.FileSystem {
+	return &httpFileSystem{root: cleanPath(root)}
+}
+
+type httpFileSystem struct {
+	root string
+}
+
+func (h *httpFileSystem) Open(name string) (http.File, error) {
+	fullName, err := joinRoot(h.root, name)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	info, err := Stat(fullName)
+	if err == nil && !info.Exists {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+
+	if info.IsDir {
+		return &httpDir{name: name, path: fullName, info: info}, nil
+	}
+
+	ioWait()
+	f, err := os.Open(longPath(fullName))
+	ioDone()
+	if err != nil {
+		errorPrinter("HTTPFileSystem (os.Open): "+err.Error(), fullName)
+		return nil, fsError("open", name, err)
+	}
+	return &httpFile{File: f, info: info}, nil
+}
+
+// httpFile streams a file from disk and describes it from the cache
+type httpFile struct {
+	*os.File
+	info FileInfo
+}
+
+func (f *httpFile) Stat() (fs.FileInfo, error) {
+	return osFileInfo{f.info}, nil
+}
+
+// httpDir is an open directory, listed from the cache on the first Readdir
+type httpDir struct {
+	name    string
+	path    string
+	info    FileInfo
+	entries []FileInfo
+	listed  bool
+}
+
+func (d *httpDir) Stat() (fs.FileInfo, error) { return osFileInfo{d.info}, nil }
+func (d *httpDir) Close() error               { return nil }
+
+func (d *httpDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *httpDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		// Rewind the listing, like os.File does
+		d.listed = false
+		return 0, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *httpDir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.listed {
+		contents, err := ReadDir(d.path)
+		if err != nil {
+			return nil, fsError("readdir", d.name, err)
+		}
+		d.entries = contents
+		d.listed = true
+	}
+
+	if count <= 0 {
+		count = len(d.entries)
+	} else if len(d.entries) == 0 {
+		return nil, io.EOF
+	} else if count > len(d.entries) {
+		count = len(d.entries)
+	}
+
+	infos := make([]fs.FileInfo, 0, count)
+	for _, entry := range d.entries[:count] {
+		infos = append(infos, osFileInfo{entry})
+	}
+	d.entries = d.entries[count:]
+	return infos, nil
+}
+
+// ListingSortKey is the column FileServer sorts directory listings by
+type ListingSortKey string
+
+const (
+	SortByName     ListingSortKey = "name"
+	SortBySize     ListingSortKey = "size"
+	SortByModified ListingSortKey = "modified"
+)
+
+type listingOptions struct {
+	sortBy     ListingSortKey
+	descending bool
+	dirsFirst  bool
+	json       bool
+}
+
+// ListingOption changes the directory listings of FileServer
+type ListingOption func(*listingOptions)
+
+// ListingSort sets the order of a listing requested without ?sort= and
+// ?order=, by name ascending when not given
+func ListingSort(by ListingSortKey, descending bool) ListingOption {
+	return func(o *listingOptions) {
+		o.sortBy = by
+		o.descending = descending
+	}
+}
+
+// ListingDirsFirst lists the directories before the files, whatever the order
+func ListingDirsFirst() ListingOption {
+	return func(o *listingOptions) { o.dirsFirst = true }
+}
+
+// ListingJSON answers requests with ?format=json or an Accept header of
+// application/json with the listing as JSON, the FileInfo of every entry
+func ListingJSON() ListingOption {
+	return func(o *listingOptions) { o.json = true }
+}
+
+// FileServer is http.FileServer over HTTPFileSystem(root), except that a
+// directory without an index.html is listed by GMSFS from the cached
+// listing: sortable by name, size or modification time with ?sort= and
+// ?order=asc|desc, and as JSON for API clients with ListingJSON.
+func FileServer(root string, opts ...ListingOption) http.Handler {
+	options := listingOptions{sortBy: SortByName}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	root = cleanPath(root)
+	return &fileServer{
+		root:    root,
+		files:   http.FileServer(HTTPFileSystem(root)),
+		options: options,
+	}
+}
+
+type fileServer struct {
+	root    string
+	files   http.Handler
+	options listingOptions
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, err := joinRoot(s.root, r.URL.Path)
+	if err != nil {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	info, err := Stat(name)
+	if err != nil || !info.Exists || !info.IsDir || !strings.HasSuffix(r.URL.Path, "/") {
+		// Files, errors and the redirect to the trailing slash
+		s.files.ServeHTTP(w, r)
+		return
+	}
+	if index, err := Stat(filepath.Join(name, "index.html")); err == nil && index.Exists && !index.IsDir {
+		s.files.ServeHTTP(w, r)
+		return
+	}
+
+	entries, err := ListFSInfo(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if os.IsNotExist(err) {
+			status = http.StatusNotFound
+		} else if os.IsPermission(err) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	sortBy, descending := s.options.sortBy, s.options.descending
+	query := r.URL.Query()
+	switch key := ListingSortKey(query.Get("sort")); key {
+	case SortByName, SortBySize, SortByModified:
+		sortBy = key
+	}
+	switch query.Get("order") {
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	}
+	sortListing(entries, sortBy, descending, s.options.dirsFirst)
+
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	if s.options.json && wantsJSON(r) {
+		agentJSON(w, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	writeListing(w, r.URL.Path, entries, sortBy, descending)
+}
+
+func wantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// sortListing orders entries by the key, ties broken by name so the order is
+// stable across requests
+func sortListing(entries []FileInfo, by ListingSortKey, descending bool, dirsFirst bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if dirsFirst && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if descending {
+			a, b = b, a
+		}
+		switch by {
+		case SortBySize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case SortByModified:
+			if !a.LastModified.Equal(b.LastModified) {
+				return a.LastModified.Before(b.LastModified)
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// writeListing writes the HTML index of the directory at urlPath, a table
+// whose headers link to the listing sorted by that column
+func writeListing(w io.Writer, urlPath string, entries []FileInfo, sortBy ListingSortKey, descending bool) {
+	header := func(key ListingSortKey, title string) string {
+		order := "asc"
+		if key == sortBy && !descending {
+			order = "desc"
+		}
+		return fmt.Sprintf(`<th><a href="?sort=%s&amp;order=%s">%s</a></th>`, key, order, title)
+	}
+
+	title := html.EscapeString(urlPath)
+	fmt.Fprintf(w, "<!doctype html>\n<html>\n<head><meta charset=\"utf-8\"><title>Index of %s</title></head>\n<body>\n<h1>Index of %s</h1>\n<table>\n", title, title)
+	fmt.Fprintf(w, "<tr>%s%s%s</tr>\n", header(SortByName, "Name"), header(SortBySize, "Size"), header(SortByModified, "Modified"))
+	if urlPath != "/" {
+		fmt.Fprintf(w, "<tr><td><a href=\"../\">../</a></td><td></td><td></td></tr>\n")
+	}
+	for _, entry := range entries {
+		name, size := path.Base(filepath.ToSlash(entry.Name)), fmt.Sprint(entry.Size)
+		if entry.IsDir {
+			name, size = name+"/", "-"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(w, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(link.String()), html.EscapeString(name), size, entry.LastModified.UTC().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(w, "</table>\n</body>\n</html>\n")
+}
@@ -0,0 +1,110 @@
+package GMSFS
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+)
+
+// InvalidationBus carries cache invalidations between processes (or hosts on
+// a shared NFS tree) that use GMSFS on the same files. Publish must deliver
+// msg to every subscriber, including ones in other processes. Close stops the
+// subscription and releases the connections, SetInvalidationBus calls it on
+// the bus it replaces.
+type InvalidationBus interface {
+	Publish(msg []byte) error
+	Subscribe(handler func(msg []byte)) error
+	Close() error
+}
+
+type invalidation struct {
+	Origin  string `json:"origin"`
+	Path    string `json:"path"`
+	Subtree bool   `json:"subtree,omitempty"` // Everything below Path changed too
+}
+
+var (
+	busMutex sync.RWMutex
+	bus      InvalidationBus
+	busID    = newBusID()
+)
+
+func newBusID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SetInvalidationBus publishes the path of every mutating operation on bus and
+// drops the cached entries of paths changed by other processes. Removing,
+// renaming or exchanging a directory invalidates its whole subtree. The bus
+// set before is closed; when b fails to subscribe no bus is left set.
+func SetInvalidationBus(b InvalidationBus) error {
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	if bus != nil {
+		if err := bus.Close(); err != nil {
+			logf("InvalidationBus (Close): %v", err)
+		}
+		bus = nil
+	}
+
+	if b != nil {
+		err := b.Subscribe(func(msg []byte) {
+			var inv invalidation
+			if err := json.Unmarshal(msg, &inv); err != nil {
+				logf("InvalidationBus (json.Unmarshal): %v", err)
+				return
+			}
+			if inv.Origin == busID {
+				return
+			}
+			if inv.Subtree {
+				CacheInvalidatePrefix(inv.Path)
+				return
+			}
+			lowerCaseName := cacheKey(inv.Path)
+			CacheDelete(lowerCaseName)
+			CacheDelete(filepath.Dir(lowerCaseName))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	bus = b
+	return nil
+}
+
+// publishInvalidation is called after every mutating operation on name
+func publishInvalidation(name string) {
+	publish(invalidation{Path: name})
+}
+
+// publishTreeInvalidation is publishInvalidation for operations that may have
+// changed a whole tree at name: a directory removed, renamed or exchanged
+func publishTreeInvalidation(name string) {
+	publish(invalidation{Path: name, Subtree: true})
+}
+
+func publish(inv invalidation) {
+	fimOwnChange(inv.Path)
+
+	busMutex.RLock()
+	b := bus
+	busMutex.RUnlock()
+	if b == nil {
+		return
+	}
+
+	// Absolute, the other processes have their own working directory
+	inv.Origin = busID
+	inv.Path = absPath(cleanPath(inv.Path))
+	msg, _ := json.Marshal(inv)
+	if err := b.Publish(msg); err != nil {
+		logf("InvalidationBus (Publish): %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package GMSFS_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// memoryBus delivers to the handlers subscribed until it is closed
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func(msg []byte)
+	closed   int
+}
+
+func (b *memoryBus) Publish(msg []byte) error {
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(handler func(msg []byte)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *memoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = nil
+	b.closed++
+	return nil
+}
+
+func TestSetInvalidationBusClosesPrevious(t *testing.T) {
+	first, second := &memoryBus{}, &memoryBus{}
+	if err := GMSFS.SetInvalidationBus(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := GMSFS.SetInvalidationBus(second); err != nil {
+		t.Fatal(err)
+	}
+	if first.closed != 1 || len(first.handlers) != 0 {
+		t.Errorf("replaced bus closed %d times with %d subscribers left, want closed once", first.closed, len(first.handlers))
+	}
+	if err := GMSFS.SetInvalidationBus(nil); err != nil {
+		t.Fatal(err)
+	}
+	if second.closed != 1 {
+		t.Errorf("bus unset with nil closed %d times, want once", second.closed)
+	}
+}
@@ -0,0 +1,71 @@
+package GMSFS
+
+import "sync"
+
+// InvestigateOptions configures the check of the cache entry behind each
+// logged error. The zero value keeps the checks and the stdout print, run
+// one at a time.
+type InvestigateOptions struct {
+	Disabled bool // Run no checks at all
+	Quiet    bool // Do not print the investigated objects to stdout
+	Workers  int  // Checks running at once, 1 when 0
+	Queue    int  // Checks waiting, 1024 when 0; errors beyond it are not checked
+}
+
+var (
+	investigateMutex   sync.RWMutex
+	investigateOptions InvestigateOptions
+	investigateQueue   chan string
+)
+
+// SetInvestigateOptions replaces how errors are investigated. Checks still
+// queued under the old options finish first.
+func SetInvestigateOptions(opts InvestigateOptions) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Queue <= 0 {
+		opts.Queue = 1024
+	}
+
+	investigateMutex.Lock()
+	defer investigateMutex.Unlock()
+	if investigateQueue != nil {
+		close(investigateQueue)
+		investigateQueue = nil
+	}
+	investigateOptions = opts
+	if opts.Disabled {
+		return
+	}
+	investigateQueue = make(chan string, opts.Queue)
+	for i := 0; i < opts.Workers; i++ {
+		go investigateWorker(investigateQueue, opts.Quiet)
+	}
+}
+
+func init() {
+	SetInvestigateOptions(InvestigateOptions{})
+}
+
+func investigateWorker(queue chan string, quiet bool) {
+	for name := range queue {
+		invistiageError(name, quiet)
+	}
+}
+
+// investigate queues the check of name, a full queue drops it
+func investigate(name string) {
+	if name == "" {
+		return
+	}
+	investigateMutex.RLock()
+	defer investigateMutex.RUnlock()
+	if investigateQueue == nil {
+		return
+	}
+	select {
+	case investigateQueue <- name:
+	default:
+	}
+}
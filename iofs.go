@@ -0,0 +1,227 @@
+package GMSFS
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// BackendFS implements fs.FS, with fs.StatFS, fs.ReadDirFS, fs.ReadFileFS
+// and fs.SubFS, over a Backend, so the cached tree can be handed to
+// html/template, http.FS, fstest.TestFS and the like. Files of a LocalBackend
+// are streamed from disk, those of other backends are read whole when opened.
+type BackendFS struct {
+	backend Backend
+}
+
+// NewFS returns the fs.FS of backend
+func NewFS(backend Backend) *BackendFS {
+	return &BackendFS{backend: backend}
+}
+
+// DirFS returns the fs.FS of the local tree at root, the cached os.DirFS
+func DirFS(root string) *BackendFS {
+	return NewFS(&LocalBackend{Root: cleanPath(root)})
+}
+
+// fsError turns err into the *fs.PathError io/fs expects, with name as the
+// path instead of the backend's
+func fsError(op string, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (b *BackendFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := b.stat(name)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+
+	if info.IsDir {
+		return &fsDir{fsys: b, name: name, info: info}, nil
+	}
+	if local, ok := b.backend.(*LocalBackend); ok {
+		fullName, err := local.path(name)
+		if err != nil {
+			return nil, fsError("open", name, err)
+		}
+		ioWait()
+		f, err := os.Open(longPath(fullName))
+		ioDone()
+		if err != nil {
+			return nil, fsError("open", name, err)
+		}
+		return f, nil
+	}
+
+	content, err := b.backend.ReadFile(name)
+	if err != nil {
+		return nil, fsError("open", name, err)
+	}
+	return &fsFile{Reader: bytes.NewReader(content), info: info}, nil
+}
+
+func (b *BackendFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := b.stat(name)
+	if err != nil {
+		return nil, fsError("stat", name, err)
+	}
+	return osFileInfo{info}, nil
+}
+
+// stat follows symlinks, like Open does: an entry taken from a cached
+// listing describes the link itself. Cache keys are case insensitive, so on
+// a disk holding both "A" and "a" the cached entry may be the other one's;
+// the name tells them apart.
+func (b *BackendFS) stat(name string) (FileInfo, error) {
+	info, err := b.backend.Stat(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if !info.Exists {
+		return FileInfo{}, fs.ErrNotExist
+	}
+	if local, ok := b.backend.(*LocalBackend); ok && (info.Mode&os.ModeSymlink != 0 || (name != "." && info.Name != path.Base(name))) {
+		fullName, err := local.path(name)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		ioWait()
+		stat, err := os.Stat(longPath(fullName))
+		ioDone()
+		if err != nil {
+			return FileInfo{}, err
+		}
+		info = FileInfo{
+			Exists:       true,
+			Size:         stat.Size(),
+			Mode:         stat.Mode(),
+			LastModified: stat.ModTime(),
+			IsDir:        stat.IsDir(),
+			Name:         stat.Name(),
+		}
+	}
+	return info, nil
+}
+
+func (b *BackendFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	contents, err := b.backend.ReadDir(name)
+	if err != nil {
+		return nil, fsError("readdir", name, err)
+	}
+
+	entries := make([]fs.DirEntry, 0, len(contents))
+	for _, entry := range contents {
+		entries = append(entries, fs.FileInfoToDirEntry(osFileInfo{entry}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (b *BackendFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := b.stat(name)
+	if err != nil {
+		return nil, fsError("readfile", name, err)
+	}
+	if info.IsDir {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errors.New("is a directory")}
+	}
+	content, err := b.backend.ReadFile(name)
+	if err != nil {
+		return nil, fsError("readfile", name, err)
+	}
+	if _, ok := b.backend.(*LocalBackend); !ok {
+		// The caller owns the slice, a backend may hand out the one it caches
+		content = append([]byte(nil), content...)
+	}
+	return content, nil
+}
+
+// Sub returns the fs.FS of the directory dir. For a LocalBackend it is the
+// DirFS of that directory, other backends are wrapped with fs.Sub.
+func (b *BackendFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return b, nil
+	}
+	if local, ok := b.backend.(*LocalBackend); ok {
+		fullDir, err := local.path(dir)
+		if err != nil {
+			return nil, fsError("sub", dir, err)
+		}
+		return DirFS(fullDir), nil
+	}
+	return fs.Sub(struct{ fs.FS }{b}, dir)
+}
+
+// fsFile is an open file of a remote backend, read into memory
+type fsFile struct {
+	*bytes.Reader
+	info FileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return osFileInfo{f.info}, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is an open directory, listed from the cache on the first ReadDir
+type fsDir struct {
+	fsys    *BackendFS
+	name    string
+	info    FileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return osFileInfo{d.info}, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.listed = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
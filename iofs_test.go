@@ -0,0 +1,65 @@
+package GMSFS_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/inpadi/GMSFS"
+	"github.com/inpadi/GMSFS/gmsfstest"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"hello.txt":         "hello, world\n",
+		"empty":             "",
+		"dir/a.txt":         "a",
+		"dir/sub/b.txt":     "bb",
+		"dir/sub/deep/c.go": "package c\n",
+	})
+	if err := os.Symlink("hello.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once from disk and once from the listings cached by the first run
+	for i := 0; i < 2; i++ {
+		if err := fstest.TestFS(GMSFS.DirFS(root), "hello.txt", "empty", "dir/a.txt", "dir/sub/b.txt", "dir/sub/deep/c.go", "link"); err != nil {
+			t.Fatal(err)
+		}
+		GMSFS.CacheWait()
+	}
+}
+
+func TestDirFSCaseCollision(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"A": "upper", "a": "lower!"})
+	if entries, _ := os.ReadDir(root); len(entries) != 2 {
+		t.Skip("case insensitive filesystem")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := fstest.TestFS(GMSFS.DirFS(root), "A", "a"); err != nil {
+			t.Fatal(err)
+		}
+		GMSFS.CacheWait()
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	gmsfstest.TestBackend(t, &GMSFS.LocalBackend{Root: t.TempDir()})
+}
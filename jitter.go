@@ -0,0 +1,22 @@
+package GMSFS
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// CacheJitter spreads expiry: every key lives up to CacheJitter longer than
+// its TTL, so entries cached at the same moment (e.g. at startup) do not all
+// expire, and get re-stat'ed, together
+var CacheJitter time.Duration
+
+// jitterFor returns a stable offset in [0, CacheJitter) for key
+func jitterFor(key string) time.Duration {
+	if CacheJitter <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(CacheJitter))
+}
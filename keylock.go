@@ -0,0 +1,41 @@
+package GMSFS
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// keyLocks serialize read-modify-write updates of cache entries. Keys are
+// spread over a fixed number of shards, so a lock must never be taken while
+// another one is held: two keys may share a shard.
+var keyLocks [256]sync.Mutex
+
+// lockKey locks the shard of key and returns the unlock function
+func lockKey(key string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	m := &keyLocks[h.Sum32()%uint32(len(keyLocks))]
+	m.Lock()
+	return m.Unlock
+}
+
+// cacheAddSync is CacheAdd that waits until the entry is visible, so the next
+// update of the key under its lock reads it instead of the previous value
+func cacheAddSync(key string, value FileInfo) {
+	CacheAdd(key, value)
+	cache.Wait()
+}
+
+// CacheUpdate replaces the cached entry of key with the result of update,
+// atomically with respect to the other updates of key. update receives the
+// current entry and whether there is one, and returns the new entry and
+// whether to store it.
+func CacheUpdate(key string, update func(info FileInfo, ok bool) (FileInfo, bool)) {
+	unlock := lockKey(key)
+	defer unlock()
+
+	info, ok := CacheGet(key)
+	if info, store := update(info, ok); store {
+		cacheAddSync(key, info)
+	}
+}
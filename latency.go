@@ -0,0 +1,180 @@
+package GMSFS
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histograms
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// OpLatency is the latency histogram of one operation on one mount
+type OpLatency struct {
+	Op    string
+	Mount string // Name from SetMounts, a CachedBackend prefix, or "local"
+	Count uint64
+	Sum   time.Duration
+	// Buckets[i] counts the calls up to LatencyBuckets[i], not cumulative;
+	// the last element counts the slower ones
+	Buckets []uint64
+}
+
+type latencyKey struct {
+	op    string
+	mount string
+}
+
+type latencyHistogram struct {
+	count   uint64
+	sum     int64
+	buckets []uint64
+}
+
+type mountRoot struct {
+	name string
+	root string // Cache key form, ends with a separator
+}
+
+var (
+	latencies   sync.Map // latencyKey -> *latencyHistogram
+	mountsValue atomic.Value
+)
+
+func init() {
+	mountsValue.Store([]mountRoot(nil))
+}
+
+// SetMounts names the directories whose calls are counted apart in the
+// latency histograms, for example {"nfs": "/mnt/nfs", "ssd": "/data"}. A path
+// belongs to the longest root containing it; the rest count as "local".
+func SetMounts(mounts map[string]string) {
+	roots := make([]mountRoot, 0, len(mounts))
+	for name, root := range mounts {
+		root = cacheKey(root)
+		if !strings.HasSuffix(root, string(os.PathSeparator)) {
+			root += string(os.PathSeparator)
+		}
+		roots = append(roots, mountRoot{name: name, root: root})
+	}
+	sort.Slice(roots, func(i, j int) bool { return len(roots[i].root) > len(roots[j].root) })
+	mountsValue.Store(roots)
+}
+
+// mountOf returns the name of the mount holding name
+func mountOf(name string) string {
+	roots := mountsValue.Load().([]mountRoot)
+	if len(roots) == 0 {
+		return "local"
+	}
+	key := cacheKey(name) + string(os.PathSeparator)
+	for _, m := range roots {
+		if strings.HasPrefix(key, m.root) {
+			return m.name
+		}
+	}
+	return "local"
+}
+
+func recordLatency(op string, mount string, elapsed time.Duration) {
+	key := latencyKey{op: op, mount: mount}
+	h, ok := latencies.Load(key)
+	if !ok {
+		h, _ = latencies.LoadOrStore(key, &latencyHistogram{buckets: make([]uint64, len(LatencyBuckets)+1)})
+	}
+	histogram := h.(*latencyHistogram)
+
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return elapsed <= LatencyBuckets[i] })
+	if i >= len(histogram.buckets) {
+		i = len(histogram.buckets) - 1 // LatencyBuckets grew after the histogram was made
+	}
+	atomic.AddUint64(&histogram.buckets[i], 1)
+	atomic.AddUint64(&histogram.count, 1)
+	atomic.AddInt64(&histogram.sum, int64(elapsed))
+}
+
+// Latencies returns the histograms of every operation and mount seen so far,
+// sorted by operation and mount
+func Latencies() []OpLatency {
+	var result []OpLatency
+	latencies.Range(func(k, v interface{}) bool {
+		key := k.(latencyKey)
+		h := v.(*latencyHistogram)
+		l := OpLatency{
+			Op:      key.op,
+			Mount:   key.mount,
+			Count:   atomic.LoadUint64(&h.count),
+			Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+			Buckets: make([]uint64, len(h.buckets)),
+		}
+		for i := range h.buckets {
+			l.Buckets[i] = atomic.LoadUint64(&h.buckets[i])
+		}
+		result = append(result, l)
+		return true
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Op != result[j].Op {
+			return result[i].Op < result[j].Op
+		}
+		return result[i].Mount < result[j].Mount
+	})
+	return result
+}
+
+// ResetLatencies drops all latency histograms
+func ResetLatencies() {
+	latencies.Range(func(k, v interface{}) bool {
+		latencies.Delete(k)
+		return true
+	})
+}
+
+// WritePrometheus writes the cache statistics and latency histograms in the
+// Prometheus text format, for a /metrics handler
+func WritePrometheus(w io.Writer) error {
+	stats := CacheStats()
+	var b strings.Builder
+
+	b.WriteString("# TYPE gmsfs_cache_entries gauge\n")
+	fmt.Fprintf(&b, "gmsfs_cache_entries{kind=\"file\"} %d\n", stats.Files)
+	fmt.Fprintf(&b, "gmsfs_cache_entries{kind=\"dir\"} %d\n", stats.Dirs)
+	fmt.Fprintf(&b, "gmsfs_cache_entries{kind=\"negative\"} %d\n", stats.Negative)
+	b.WriteString("# TYPE gmsfs_cache_bytes gauge\n")
+	fmt.Fprintf(&b, "gmsfs_cache_bytes %d\n", stats.Bytes)
+	b.WriteString("# TYPE gmsfs_cache_hits_total counter\n")
+	fmt.Fprintf(&b, "gmsfs_cache_hits_total %d\n", stats.Hits)
+	b.WriteString("# TYPE gmsfs_cache_misses_total counter\n")
+	fmt.Fprintf(&b, "gmsfs_cache_misses_total %d\n", stats.Misses)
+
+	b.WriteString("# TYPE gmsfs_op_duration_seconds histogram\n")
+	for _, l := range stats.Latency {
+		labels := "op=" + strconv.Quote(l.Op) + ",mount=" + strconv.Quote(l.Mount)
+		var cumulative uint64
+		for i, bound := range LatencyBuckets {
+			if i < len(l.Buckets) {
+				cumulative += l.Buckets[i]
+			}
+			fmt.Fprintf(&b, "gmsfs_op_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound.Seconds(), cumulative)
+		}
+		fmt.Fprintf(&b, "gmsfs_op_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, l.Count)
+		fmt.Fprintf(&b, "gmsfs_op_duration_seconds_sum{%s} %g\n", labels, l.Sum.Seconds())
+		fmt.Fprintf(&b, "gmsfs_op_duration_seconds_count{%s} %d\n", labels, l.Count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
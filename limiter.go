@@ -0,0 +1,125 @@
+package GMSFS
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Limiter bounds the number of concurrent calls and, optionally, the number
+// of calls per second. Limits can be changed while the Limiter is in use.
+type Limiter struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	max      int
+	active   int
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter allows concurrency calls at a time and opsPerSecond calls per
+// second, 0 means no limit for either
+func NewLimiter(concurrency int, opsPerSecond float64) *Limiter {
+	l := &Limiter{}
+	l.cond = sync.NewCond(&l.mutex)
+	l.SetLimits(concurrency, opsPerSecond)
+	return l
+}
+
+func (l *Limiter) SetLimits(concurrency int, opsPerSecond float64) {
+	l.mutex.Lock()
+	l.max = concurrency
+	l.interval = 0
+	if opsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / opsPerSecond)
+	}
+	l.mutex.Unlock()
+	l.cond.Broadcast()
+}
+
+// Acquire waits for a free slot (and the rate limit), call Release when done
+func (l *Limiter) Acquire() {
+	l.mutex.Lock()
+	for l.max > 0 && l.active >= l.max {
+		l.cond.Wait()
+	}
+	l.active++
+
+	var wait time.Duration
+	if l.interval > 0 {
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+		wait = l.next.Sub(now)
+		l.next = l.next.Add(l.interval)
+	}
+	l.mutex.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (l *Limiter) Release() {
+	l.mutex.Lock()
+	l.active--
+	l.mutex.Unlock()
+	l.cond.Signal()
+}
+
+// ioLimiter governs the OS calls of the package itself
+var ioLimiter = NewLimiter(0, 0)
+
+// SetIOLimits bounds the concurrent OS calls issued by GMSFS, and the calls
+// per second when opsPerSecond > 0, so a burst of cache misses cannot
+// overwhelm a network filesystem. Cache hits are not limited.
+func SetIOLimits(concurrency int, opsPerSecond float64) {
+	ioLimiter.SetLimits(concurrency, opsPerSecond)
+}
+
+func ioWait() { ioLimiter.Acquire() }
+func ioDone() { ioLimiter.Release() }
+
+// LimitedBackend applies its own Limiter to every call of Backend
+type LimitedBackend struct {
+	Backend Backend
+	Limiter *Limiter
+}
+
+func NewLimitedBackend(backend Backend, limiter *Limiter) *LimitedBackend {
+	return &LimitedBackend{Backend: backend, Limiter: limiter}
+}
+
+// PrefixDirs forwards the prefixDirs interface of Backend
+func (lb *LimitedBackend) PrefixDirs() bool { return hasPrefixDirs(lb.Backend) }
+
+func (lb *LimitedBackend) Stat(name string) (FileInfo, error) {
+	lb.Limiter.Acquire()
+	defer lb.Limiter.Release()
+	return lb.Backend.Stat(name)
+}
+
+func (lb *LimitedBackend) ReadDir(name string) ([]FileInfo, error) {
+	lb.Limiter.Acquire()
+	defer lb.Limiter.Release()
+	return lb.Backend.ReadDir(name)
+}
+
+func (lb *LimitedBackend) ReadFile(name string) ([]byte, error) {
+	lb.Limiter.Acquire()
+	defer lb.Limiter.Release()
+	return lb.Backend.ReadFile(name)
+}
+
+func (lb *LimitedBackend) WriteFile(name string, content []byte, perm os.FileMode) error {
+	lb.Limiter.Acquire()
+	defer lb.Limiter.Release()
+	return lb.Backend.WriteFile(name, content, perm)
+}
+
+func (lb *LimitedBackend) Remove(name string) error {
+	lb.Limiter.Acquire()
+	defer lb.Limiter.Release()
+	return lb.Backend.Remove(name)
+}
@@ -0,0 +1,53 @@
+package GMSFS_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// With GMSFS.Debug present failures are logged through Append, which takes
+// an I/O slot of its own: a call still holding one deadlocks a limit of 1
+func TestIOLimitReleasedBeforeLogging(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.WriteFile("GMSFS.Debug", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	GMSFS.SetIOLimits(1, 0)
+	defer GMSFS.SetIOLimits(0, 0)
+
+	missing := filepath.Join(dir, "missing")
+	calls := map[string]func() error{
+		"Mmap":          func() error { _, err := GMSFS.Mmap(missing); return err },
+		"ReadFileRange": func() error { _, err := GMSFS.ReadFileRange(missing, 0, 1); return err },
+		"ReadFileInto":  func() error { var buf []byte; _, err := GMSFS.ReadFileInto(missing, &buf); return err },
+		"SyncDir":       func() error { return GMSFS.SyncDir(missing) },
+	}
+	if runtime.GOOS == "windows" {
+		delete(calls, "SyncDir") // does nothing there
+	}
+	for name, call := range calls {
+		done := make(chan error, 1)
+		go func() { done <- call() }()
+		select {
+		case err := <-done:
+			if !os.IsNotExist(err) {
+				t.Errorf("%s = %v, want not exist", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s did not return, its I/O slot was held while logging", name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package GMSFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ListFSInfo returns the entries of the directory path, like ListFS but as
+// FileInfo values instead of "*" prefixed names
+func ListFSInfo(path string) ([]FileInfo, error) {
+	info, err := Stat(path)
+	if err != nil {
+		errorPrinter("ListFSInfo (Stat): "+err.Error(), path)
+		return nil, err
+	}
+	if !info.IsDir {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	contents, err := ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]FileInfo, 0, len(contents))
+	for _, entry := range contents {
+		entry.Contents = nil
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// fileInfoJSON fixes the JSON field names of FileInfo, they are part of the
+// HTTP APIs and must not change when FileInfo fields are renamed
+type fileInfoJSON struct {
+	Name         string      `json:"name"`
+	Exists       bool        `json:"exists"`
+	IsDir        bool        `json:"is_dir"`
+	Size         int64       `json:"size"`
+	Mode         os.FileMode `json:"mode"`
+	LastModified time.Time   `json:"last_modified"`
+	SHA256       string      `json:"sha256,omitempty"`
+	Allocated    int64       `json:"allocated,omitempty"`
+	ContentType  string      `json:"content_type,omitempty"`
+	Contents     []FileInfo  `json:"contents,omitempty"`
+	CacheTime    *time.Time  `json:"cache_time,omitempty"`
+}
+
+func (fi FileInfo) MarshalJSON() ([]byte, error) {
+	v := fileInfoJSON{
+		Name:         fi.Name,
+		Exists:       fi.Exists,
+		IsDir:        fi.IsDir,
+		Size:         fi.Size,
+		Mode:         fi.Mode,
+		LastModified: fi.LastModified,
+		SHA256:       fi.SHA256,
+		Allocated:    fi.Allocated,
+		ContentType:  fi.ContentType,
+		Contents:     fi.Contents,
+	}
+	if !fi.CacheTime.IsZero() {
+		v.CacheTime = &fi.CacheTime
+	}
+	return json.Marshal(v)
+}
+
+func (fi *FileInfo) UnmarshalJSON(data []byte) error {
+	var v fileInfoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*fi = FileInfo{
+		Name:         v.Name,
+		Exists:       v.Exists,
+		IsDir:        v.IsDir,
+		Size:         v.Size,
+		Mode:         v.Mode,
+		LastModified: v.LastModified,
+		SHA256:       v.SHA256,
+		Allocated:    v.Allocated,
+		ContentType:  v.ContentType,
+		Contents:     v.Contents,
+	}
+	if v.CacheTime != nil {
+		fi.CacheTime = *v.CacheTime
+	}
+	return nil
+}
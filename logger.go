@@ -0,0 +1,31 @@
+package GMSFS
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// Logger receives the log output of GMSFS, *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type loggerHolder struct{ Logger }
+
+var currentLogger atomic.Value
+
+func init() {
+	currentLogger.Store(loggerHolder{log.Default()})
+}
+
+// SetLogger sends GMSFS log output to l, nil restores the standard logger
+func SetLogger(l Logger) {
+	if l == nil {
+		l = log.Default()
+	}
+	currentLogger.Store(loggerHolder{l})
+}
+
+func logf(format string, v ...interface{}) {
+	currentLogger.Load().(loggerHolder).Printf(format, v...)
+}
@@ -0,0 +1,250 @@
+package GMSFS
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ManifestMissing = "missing" // listed in the manifest, gone on disk
+	ManifestExtra   = "extra"   // on disk, not listed in the manifest
+	ManifestSize    = "size"    // size differs
+	ManifestHash    = "hash"    // same size, different content
+	ManifestModTime = "mtime"   // content matches, modification time differs
+)
+
+// ManifestEntry describes one file of a manifest. Path is relative to the
+// manifest root and always uses forward slashes.
+type ManifestEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// ManifestMismatch is a file that does not match its manifest entry
+type ManifestMismatch struct {
+	Path     string
+	Kind     string
+	Expected ManifestEntry
+	Actual   ManifestEntry
+}
+
+var manifestHeader = []string{"path", "size", "mtime", "sha256"}
+
+// BuildManifest lists every regular file below root with its size,
+// modification time and SHA256, sorted by path. Symlinks are skipped.
+func BuildManifest(root string) ([]ManifestEntry, error) {
+	return buildManifest(root, "")
+}
+
+// WriteManifest writes the manifest of root to manifestPath. A ".csv"
+// extension selects CSV, anything else JSON. The manifest file itself is left
+// out when it lives below root.
+func WriteManifest(root string, manifestPath string) (err error) {
+	entries, err := buildManifest(root, manifestPath)
+	if err != nil {
+		return err
+	}
+
+	tmp := manifestPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		errorPrinter("WriteManifest (os.Create): "+err.Error(), tmp)
+		return err
+	}
+
+	if isCSVManifest(manifestPath) {
+		err = writeManifestCSV(f, entries)
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(entries)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		errorPrinter("WriteManifest (encode): "+err.Error(), manifestPath)
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, manifestPath); err != nil {
+		return err
+	}
+	CacheDelete(cacheKey(manifestPath))
+	CacheDelete(cacheKey(filepath.Dir(cleanPath(manifestPath))))
+	return nil
+}
+
+// ReadManifest loads a manifest written by WriteManifest
+func ReadManifest(manifestPath string) ([]ManifestEntry, error) {
+	f, err := os.Open(longPath(manifestPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if isCSVManifest(manifestPath) {
+		return readManifestCSV(f)
+	}
+
+	var entries []ManifestEntry
+	if err := json.NewDecoder(f).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// VerifyManifest compares the files below root with the manifest at
+// manifestPath and returns every mismatch, sorted by path. The cache below
+// root is dropped first so the comparison reflects the disk.
+func VerifyManifest(root string, manifestPath string) ([]ManifestMismatch, error) {
+	expected, err := ReadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	CacheInvalidatePrefix(root)
+	actual, err := buildManifest(root, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareManifests(expected, actual), nil
+}
+
+func compareManifests(expected []ManifestEntry, actual []ManifestEntry) []ManifestMismatch {
+	current := make(map[string]ManifestEntry, len(actual))
+	for _, entry := range actual {
+		current[entry.Path] = entry
+	}
+
+	var mismatches []ManifestMismatch
+	for _, want := range expected {
+		got, ok := current[want.Path]
+		delete(current, want.Path)
+
+		kind := ""
+		switch {
+		case !ok:
+			kind = ManifestMissing
+		case got.Size != want.Size:
+			kind = ManifestSize
+		case got.SHA256 != want.SHA256:
+			kind = ManifestHash
+		case !got.ModTime.Equal(want.ModTime):
+			kind = ManifestModTime
+		}
+		if kind != "" {
+			mismatches = append(mismatches, ManifestMismatch{Path: want.Path, Kind: kind, Expected: want, Actual: got})
+		}
+	}
+	for _, got := range current {
+		mismatches = append(mismatches, ManifestMismatch{Path: got.Path, Kind: ManifestExtra, Actual: got})
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Path < mismatches[j].Path })
+	return mismatches
+}
+
+func buildManifest(root string, manifestPath string) ([]ManifestEntry, error) {
+	root = cleanPath(root)
+	skip := ""
+	if manifestPath != "" {
+		skip = cacheKey(manifestPath)
+	}
+
+	files, err := Find(root, FindOfType(FindFile))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, 0, len(files))
+	for _, info := range files {
+		if cacheKey(info.Name) == skip || cacheKey(info.Name) == skip+".tmp" {
+			continue
+		}
+
+		sum, err := FileSHA256(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, info.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ManifestEntry{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size,
+			ModTime: info.LastModified,
+			SHA256:  sum,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+func isCSVManifest(manifestPath string) bool {
+	return strings.EqualFold(filepath.Ext(manifestPath), ".csv")
+}
+
+func writeManifestCSV(w io.Writer, entries []ManifestEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(manifestHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		record := []string{
+			entry.Path,
+			strconv.FormatInt(entry.Size, 10),
+			entry.ModTime.Format(time.RFC3339Nano),
+			entry.SHA256,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func readManifestCSV(r io.Reader) ([]ManifestEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var entries []ManifestEntry
+	for i, record := range records[1:] {
+		if len(record) != len(manifestHeader) {
+			return nil, fmt.Errorf("manifest line %d: expected %d fields, got %d", i+2, len(manifestHeader), len(record))
+		}
+		size, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %v", i+2, err)
+		}
+		modTime, err := time.Parse(time.RFC3339Nano, record[2])
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %v", i+2, err)
+		}
+		entries = append(entries, ManifestEntry{Path: record[0], Size: size, ModTime: modTime, SHA256: record[3]})
+	}
+	return entries, nil
+}
@@ -0,0 +1,146 @@
+package GMSFS
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy is what MergeDirs does when an entry exists in both trees
+type ConflictPolicy int
+
+const (
+	// ConflictSkip keeps the destination entry
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the destination entry with the source one
+	ConflictOverwrite
+	// ConflictKeepNewer replaces the destination entry only when the source
+	// one was modified later
+	ConflictKeepNewer
+	// ConflictRename copies the source entry next to the destination one
+	// under the first free name(1).ext, name(2).ext, ... like
+	// NextAvailableName
+	ConflictRename
+)
+
+// MergeDirs copies the tree src into the existing directory dst. Entries only
+// in src are copied, directories in both are merged recursively and other
+// conflicts, including a file where the other tree has a directory, are
+// settled by policy. Modification times come from the cache. opts apply as in
+// CopyDir.
+func MergeDirs(src string, dst string, policy ConflictPolicy, opts ...CopyOption) (err error) {
+	defer startOp("MergeDirs", src).end(&err)
+	if err := injectedFault("MergeDirs", src); err != nil {
+		return err
+	}
+	if planned("merge", src, dst) {
+		return nil
+	}
+	src = cleanPath(src)
+	dst = cleanPath(dst)
+
+	for _, dir := range []string{src, dst} {
+		info, err := Stat(dir)
+		if err != nil {
+			errorPrinter("MergeDirs (Stat): "+err.Error(), dir)
+			return err
+		}
+		if !info.IsDir {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+	}
+
+	options := applyCopyOptions(opts)
+	return mergeDir(src, dst, "", policy, &options)
+}
+
+func mergeDir(src string, dst string, rel string, policy ConflictPolicy, options *copyOptions) error {
+	entries, err := ReadDir(src)
+	if err != nil {
+		errorPrinter("MergeDirs (ReadDir): "+err.Error(), src)
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name)
+		dstPath := filepath.Join(dst, entry.Name)
+		entryRel := filepath.Join(rel, entry.Name)
+		if !options.selected(entryRel, entry) {
+			continue
+		}
+		if entry.Mode&os.ModeSymlink != 0 && options.symlinks == SymlinkSkip {
+			continue
+		}
+
+		existing, err := Stat(dstPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		exists := err == nil && existing.Exists
+
+		if exists && entry.IsDir && existing.IsDir && entry.Mode&os.ModeSymlink == 0 {
+			if err := mergeDir(srcPath, dstPath, entryRel, policy, options); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if exists {
+			switch policy {
+			case ConflictSkip:
+				continue
+			case ConflictKeepNewer:
+				if !entry.LastModified.After(existing.LastModified) {
+					continue
+				}
+				fallthrough
+			case ConflictOverwrite:
+				if err := RemoveAll(dstPath); err != nil {
+					return err
+				}
+			case ConflictRename:
+				if dstPath, err = mergeRenameTarget(dst, entry.Name); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown conflict policy %d", policy)
+			}
+		}
+
+		if err := mergeCopy(srcPath, dstPath, entryRel, entry, options); err != nil {
+			return err
+		}
+	}
+
+	return UpdateDirectoryContents(dst)
+}
+
+// mergeRenameTarget picks the free name in dir for a conflicting entry.
+// NextAvailableName reserves it as an empty file, which the copy replaces.
+func mergeRenameTarget(dir string, name string) (string, error) {
+	ext := filepath.Ext(name)
+	target, err := NextAvailableName(dir, strings.TrimSuffix(name, ext), ext)
+	if err != nil {
+		if target != "" {
+			Remove(target)
+		}
+		return "", err
+	}
+	if err := Remove(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// mergeCopy copies the entry src to the free path dst
+func mergeCopy(src string, dst string, rel string, entry FileInfo, options *copyOptions) error {
+	switch {
+	case entry.Mode&os.ModeSymlink != 0:
+		return copySymlink(src, dst, rel, options)
+	case entry.IsDir:
+		return copyDir(src, dst, rel, options)
+	default:
+		return CopyFile(src, dst, options.fileOptions()...)
+	}
+}
@@ -0,0 +1,43 @@
+package GMSFS
+
+import (
+	"os"
+)
+
+// preserveMetadata copies the times, owner and extended attributes of src,
+// described by stat, to dst
+func preserveMetadata(src string, dst string, stat os.FileInfo) error {
+	ioWait()
+	defer ioDone()
+
+	if err := copyXattrs(src, dst); err != nil {
+		return err
+	}
+	if err := copyOwner(dst, stat); err != nil {
+		return err
+	}
+	// Again after chown, which clears setuid and setgid
+	if err := os.Chmod(longPath(dst), stat.Mode()); err != nil {
+		return err
+	}
+	// Times last, the other changes may touch them
+	err := os.Chtimes(longPath(dst), fileAtime(stat), stat.ModTime())
+	CacheDelete(cacheKey(dst))
+	return err
+}
+
+// preserveLinkMetadata copies the owner and times of the symlink src to the
+// symlink dst, without following them
+func preserveLinkMetadata(src string, dst string) error {
+	ioWait()
+	defer ioDone()
+
+	stat, err := os.Lstat(longPath(src))
+	if err != nil {
+		return err
+	}
+	if err := copyOwner(dst, stat); err != nil {
+		return err
+	}
+	return setLinkTimes(dst, fileAtime(stat), stat.ModTime())
+}
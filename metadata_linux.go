@@ -0,0 +1,81 @@
+package GMSFS
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func fileAtime(stat os.FileInfo) time.Time {
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return stat.ModTime()
+}
+
+// copyOwner gives dst the owner and group of stat, which only root (or
+// CAP_CHOWN) may do; without permission the copy keeps the caller's
+func copyOwner(dst string, stat os.FileInfo) error {
+	st, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := os.Lchown(longPath(dst), int(st.Uid), int(st.Gid))
+	if errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	return err
+}
+
+// copyXattrs copies the extended attributes of src to dst. Filesystems
+// without them, and attributes the caller may not set (trusted.*,
+// security.* as non-root), are skipped.
+func copyXattrs(src string, dst string) error {
+	size, err := unix.Llistxattr(longPath(src), nil)
+	if err != nil || size == 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(longPath(src), buf); err != nil {
+		return nil
+	}
+
+	for _, name := range splitXattrNames(buf[:size]) {
+		vsize, err := unix.Lgetxattr(longPath(src), name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, vsize)
+		if vsize, err = unix.Lgetxattr(longPath(src), name, value); err != nil {
+			continue
+		}
+		err = unix.Lsetxattr(longPath(dst), name, value[:vsize], 0)
+		if err != nil && err != unix.ENOTSUP && err != unix.EPERM && err != unix.EACCES {
+			return &os.PathError{Op: "setxattr", Path: dst, Err: err}
+		}
+	}
+	return nil
+}
+
+// splitXattrNames splits the NUL separated list of Llistxattr
+func splitXattrNames(buf []byte) []string {
+	var names []string
+	start := 0
+	for i, c := range buf {
+		if c == 0 {
+			if i > start {
+				names = append(names, string(buf[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
+
+func setLinkTimes(dst string, atime time.Time, mtime time.Time) error {
+	ts := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	return unix.UtimesNanoAt(unix.AT_FDCWD, longPath(dst), ts, unix.AT_SYMLINK_NOFOLLOW)
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package GMSFS
+
+import (
+	"os"
+	"time"
+)
+
+// fileAtime has no portable source outside Linux, the modification time
+// stands in
+func fileAtime(stat os.FileInfo) time.Time {
+	return stat.ModTime()
+}
+
+func copyOwner(dst string, stat os.FileInfo) error {
+	return nil
+}
+
+func copyXattrs(src string, dst string) error {
+	return nil
+}
+
+func setLinkTimes(dst string, atime time.Time, mtime time.Time) error {
+	return nil
+}
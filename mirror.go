@@ -0,0 +1,352 @@
+package GMSFS
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MirrorOptions configures Mirror
+type MirrorOptions struct {
+	// Interval between scans of the source, WaitPollInterval when 0
+	Interval time.Duration
+	// Delete removes entries from the destination that disappear from the
+	// source
+	Delete bool
+	// Conflict settles files changed in the destination since Mirror last
+	// wrote them, or differing from the source on the initial sync. The zero
+	// value ConflictSkip leaves them alone, ConflictOverwrite mirrors like
+	// rsync.
+	Conflict ConflictPolicy
+	// Copy filters the mirrored entries and sets the symlink policy like for
+	// CopyDir. Metadata is always preserved, Mirror compares times.
+	Copy []CopyOption
+}
+
+// MirrorConflict reports a destination entry that did not match what Mirror
+// expected, and the policy it was settled with
+type MirrorConflict struct {
+	Path        string // Relative to the source and destination roots
+	Source      FileInfo
+	Destination FileInfo
+	Policy      ConflictPolicy
+}
+
+// Mirroring is a running Mirror. Conflicts and Errors are buffered; reports
+// that find them full are dropped (and logged), so an unread channel never
+// stalls the mirror.
+type Mirroring struct {
+	Conflicts <-chan MirrorConflict
+	Errors    <-chan error
+
+	conflicts chan MirrorConflict
+	errors    chan error
+	done      chan struct{}
+}
+
+// Done is closed when the mirror stopped after ctx was cancelled
+func (m *Mirroring) Done() <-chan struct{} {
+	return m.done
+}
+
+// Mirror keeps dst a copy of the tree src: it syncs everything once, then
+// scans src every Interval and applies the difference to dst through the
+// cache-aware functions, so the cache and dst never disagree the way an
+// external rsync does. The initial sync has finished when Mirror returns.
+func Mirror(ctx context.Context, src string, dst string, opts MirrorOptions) (*Mirroring, error) {
+	src = cleanPath(src)
+	dst = cleanPath(dst)
+	if opts.Interval <= 0 {
+		opts.Interval = WaitPollInterval
+	}
+
+	current, err := scanTree(src)
+	if err != nil {
+		errorPrinter("Mirror (scanTree): "+err.Error(), src)
+		return nil, err
+	}
+	if err := EnsureDir(dst, 0755); err != nil {
+		return nil, err
+	}
+
+	conflicts := make(chan MirrorConflict, 64)
+	errs := make(chan error, 64)
+	m := &Mirroring{Conflicts: conflicts, Errors: errs, conflicts: conflicts, errors: errs, done: make(chan struct{})}
+	mr := &mirrorRun{m: m, src: src, dst: dst, opts: opts, options: applyCopyOptions(opts.Copy), synced: map[string]FileInfo{}}
+	mr.options.preserve = true
+
+	for _, rel := range sortedKeys(current) {
+		mr.update(rel, current, true)
+	}
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		previous := current
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := scanTree(src)
+			if err != nil {
+				// Keep the destination as it is, src may only be unmounted
+				mr.report(err)
+				continue
+			}
+
+			changes := diffListings("", previous, current)
+			sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+			for _, change := range changes {
+				if change.Op == WatchRemove {
+					mr.remove(change.Path, previous)
+				} else {
+					mr.update(change.Path, current, false)
+				}
+			}
+			previous = current
+		}
+	}()
+
+	return m, nil
+}
+
+type mirrorRun struct {
+	m       *Mirroring
+	src     string
+	dst     string
+	opts    MirrorOptions
+	options copyOptions
+	synced  map[string]FileInfo // What dst looked like after Mirror wrote it
+}
+
+func (mr *mirrorRun) report(err error) {
+	select {
+	case mr.m.errors <- err:
+	default:
+		logf("Mirror: %v", err)
+	}
+}
+
+func (mr *mirrorRun) conflict(c MirrorConflict) {
+	select {
+	case mr.m.conflicts <- c:
+	default:
+		logf("Mirror: conflict on %s", c.Path)
+	}
+}
+
+// selected applies the copy filters to rel and each of its parents
+func (mr *mirrorRun) selected(rel string, tree map[string]FileInfo) bool {
+	for p := rel; p != "." && p != ""; p = filepath.Dir(p) {
+		info, ok := tree[p]
+		if !ok || !mr.options.selected(p, info) {
+			return false
+		}
+	}
+	return true
+}
+
+// update copies the created or modified source entry rel to dst
+func (mr *mirrorRun) update(rel string, tree map[string]FileInfo, initial bool) {
+	info := tree[rel]
+	if !mr.selected(rel, tree) {
+		return
+	}
+	if info.Mode&os.ModeSymlink != 0 && mr.options.symlinks == SymlinkSkip {
+		return
+	}
+	srcPath := filepath.Join(mr.src, rel)
+	dstPath := filepath.Join(mr.dst, rel)
+
+	existing, exists := lstatInfo(dstPath)
+	if info.IsDir {
+		if exists && existing.IsDir {
+			return
+		}
+		if exists {
+			// A file where src has a directory
+			if !mr.settle(rel, info, existing) {
+				return
+			}
+			if err := RemoveAll(dstPath); err != nil {
+				mr.report(err)
+				return
+			}
+		}
+		if err := EnsureDir(dstPath, info.Mode.Perm()); err != nil {
+			mr.report(err)
+		}
+		return
+	}
+
+	if exists {
+		var changed bool
+		if initial {
+			if changed = !sameFileState(existing, info); !changed {
+				mr.synced[rel] = existing
+				return
+			}
+		} else {
+			synced, known := mr.synced[rel]
+			changed = !known || !sameFileState(existing, synced)
+		}
+
+		if changed {
+			if !mr.settle(rel, info, existing) {
+				return
+			}
+			if mr.opts.Conflict == ConflictRename {
+				target, err := mergeRenameTarget(filepath.Dir(dstPath), filepath.Base(dstPath))
+				if err == nil {
+					err = mergeCopy(srcPath, target, rel, info, &mr.options)
+				}
+				if err != nil {
+					mr.report(err)
+				}
+				return
+			}
+		}
+		if existing.IsDir || existing.Mode&os.ModeSymlink != 0 {
+			if err := RemoveAll(dstPath); err != nil {
+				mr.report(err)
+				return
+			}
+		}
+	}
+
+	if err := mergeCopy(srcPath, dstPath, rel, info, &mr.options); err != nil {
+		mr.report(err)
+		return
+	}
+	if copied, ok := lstatInfo(dstPath); ok {
+		mr.synced[rel] = copied
+	}
+}
+
+// settle reports a conflict on rel and tells whether the policy lets the
+// source entry replace the destination one (or, with ConflictRename, be
+// copied next to it)
+func (mr *mirrorRun) settle(rel string, info FileInfo, existing FileInfo) bool {
+	mr.conflict(MirrorConflict{Path: rel, Source: info, Destination: existing, Policy: mr.opts.Conflict})
+
+	switch mr.opts.Conflict {
+	case ConflictOverwrite, ConflictRename:
+		return true
+	case ConflictKeepNewer:
+		return info.LastModified.After(existing.LastModified)
+	}
+	return false
+}
+
+// remove deletes rel, which disappeared from the source, from dst unless it
+// was changed there since the last sync
+func (mr *mirrorRun) remove(rel string, tree map[string]FileInfo) {
+	if !mr.opts.Delete || !mr.selected(rel, tree) {
+		return
+	}
+	info := tree[rel]
+	dstPath := filepath.Join(mr.dst, rel)
+	existing, exists := lstatInfo(dstPath)
+	if !exists {
+		return
+	}
+
+	if synced, known := mr.synced[rel]; !existing.IsDir && (!known || !sameFileState(existing, synced)) {
+		mr.conflict(MirrorConflict{Path: rel, Source: info, Destination: existing, Policy: ConflictSkip})
+		return
+	}
+	if err := RemoveAll(dstPath); err != nil {
+		mr.report(err)
+		return
+	}
+
+	prefix := rel + string(os.PathSeparator)
+	for name := range mr.synced {
+		if name == rel || strings.HasPrefix(name, prefix) {
+			delete(mr.synced, name)
+		}
+	}
+}
+
+// scanTree lists everything below root straight from disk, keyed by the path
+// relative to root
+func scanTree(root string) (map[string]FileInfo, error) {
+	tree := map[string]FileInfo{}
+	err := filepath.WalkDir(longPath(root), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == longPath(root) {
+				return err
+			}
+			return nil // removed while walking
+		}
+		if name == longPath(root) {
+			if !d.IsDir() {
+				return fmt.Errorf("%s is not a directory", root)
+			}
+			return nil
+		}
+
+		stat, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(longPath(root), name)
+		if err != nil {
+			return err
+		}
+		tree[rel] = FileInfo{
+			Exists:       true,
+			Size:         stat.Size(),
+			Mode:         stat.Mode(),
+			LastModified: stat.ModTime(),
+			IsDir:        stat.IsDir(),
+			Name:         stat.Name(),
+			CacheTime:    clockNow(),
+		}
+		return nil
+	})
+
+	return tree, err
+}
+
+// lstatInfo describes name from disk, without following a symlink
+func lstatInfo(name string) (FileInfo, bool) {
+	ioWait()
+	stat, err := os.Lstat(longPath(name))
+	ioDone()
+	if err != nil {
+		return FileInfo{}, false
+	}
+	return FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		IsDir:        stat.IsDir(),
+		Name:         stat.Name(),
+	}, true
+}
+
+func sameFileState(a FileInfo, b FileInfo) bool {
+	return a.IsDir == b.IsDir && a.Size == b.Size && a.LastModified.Equal(b.LastModified)
+}
+
+func sortedKeys(tree map[string]FileInfo) []string {
+	keys := make([]string, 0, len(tree))
+	for key := range tree {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package GMSFS
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ReadOnlyMmap is a read-only memory mapping of a whole file, see Mmap
+type ReadOnlyMmap interface {
+	io.ReaderAt
+	io.Closer
+	// Bytes returns the mapped contents, valid until Close. Writing to the
+	// slice faults.
+	Bytes() []byte
+	Len() int
+}
+
+type mmapFile struct {
+	data   []byte
+	unmap  func() error
+	closed bool
+}
+
+// Mmap maps name into memory for zero-copy reads. The file must not be
+// truncated while mapped. Its metadata is added to the cache on the way.
+func Mmap(name string) (_ ReadOnlyMmap, err error) {
+	op := startOp("Mmap", name)
+	defer op.end(&err)
+	if err := injectedFault("Mmap", name); err != nil {
+		return nil, err
+	}
+	name = cleanPath(name)
+
+	ioWait()
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		ioDone()
+		errorPrinter("Mmap (os.Open): "+err.Error(), name)
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		ioDone()
+		errorPrinter("Mmap (Stat): "+err.Error(), name)
+		return nil, err
+	}
+	if stat.IsDir() {
+		f.Close()
+		ioDone()
+		return nil, fmt.Errorf("%s is a directory", name)
+	}
+	m := &mmapFile{unmap: func() error { return nil }}
+	if stat.Size() > 0 {
+		m.data, m.unmap, err = mmap(f, stat.Size())
+	}
+	// The mapping stays valid after the descriptor is closed
+	f.Close()
+	ioDone()
+
+	CacheAdd(cacheKey(name), FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		Name:         filepath.Base(name),
+		CacheTime:    clockNow(),
+	})
+
+	if err != nil {
+		errorPrinter("Mmap (mmap): "+err.Error(), name)
+		return nil, err
+	}
+	op.bytes(stat.Size())
+
+	return m, nil
+}
+
+func (m *mmapFile) Bytes() []byte { return m.data }
+
+func (m *mmapFile) Len() int { return len(m.data) }
+
+func (m *mmapFile) ReadAt(p []byte, off int64) (int, error) {
+	if m.closed {
+		return 0, os.ErrClosed
+	}
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *mmapFile) Close() error {
+	if m.closed {
+		return os.ErrClosed
+	}
+	m.closed = true
+	m.data = nil
+	return m.unmap()
+}
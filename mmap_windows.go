@@ -0,0 +1,30 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmap(f *os.File, size int64) ([]byte, func() error, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, uint32(size>>32), uint32(size), nil)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+
+	// Convert through a pointer to addr, the view is not Go memory so a plain
+	// uintptr conversion would be flagged as pointer misuse
+	data := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), int(size))
+	unmap := func() error {
+		err := syscall.UnmapViewOfFile(addr)
+		syscall.CloseHandle(h)
+		return err
+	}
+	return data, unmap, nil
+}
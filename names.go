@@ -0,0 +1,130 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest name in bytes SanitizeName returns, the limit
+// of a path component on common filesystems
+var MaxNameLength = 255
+
+// windowsReserved are device names Windows refuses as file names, with any
+// extension
+var windowsReserved = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// SanitizeName turns name into a valid single path component: path
+// separators, control characters and, with WindowsPaths, the characters
+// <>:"|?* are replaced by "_", trailing dots and spaces and reserved device
+// names (CON, NUL, COM1...) are avoided on Windows, and the result is cut to
+// MaxNameLength bytes keeping the extension.
+func SanitizeName(name string) string {
+	name = strings.ToValidUTF8(name, "_")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r < 0x20 || r == 0x7f:
+			return '_'
+		case WindowsPaths && strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+
+	if WindowsPaths {
+		name = strings.TrimRight(name, ". ")
+		stem := name
+		if i := strings.IndexByte(stem, '.'); i >= 0 {
+			stem = stem[:i]
+		}
+		if windowsReserved[strings.ToLower(strings.TrimRight(stem, " "))] {
+			name = "_" + name
+		}
+	}
+	if name == "" || name == "." || name == ".." {
+		name = strings.Repeat("_", len(name)+1)
+	}
+
+	if len(name) > MaxNameLength {
+		ext := filepath.Ext(name)
+		if len(ext) >= MaxNameLength/2 {
+			ext = ""
+		}
+		stem := name[:MaxNameLength-len(ext)]
+		for !utf8.ValidString(stem) {
+			stem = stem[:len(stem)-1]
+		}
+		name = stem + ext
+	}
+	return name
+}
+
+var nextNameMutex sync.Mutex
+
+// NextAvailableName returns the path of the first free name in dir among
+// base+ext, base(1)+ext, base(2)+ext, ... ext includes the dot, e.g. ".pdf".
+// Names are compared with the cached directory listing the way the cache
+// compares keys (case-insensitively), and the chosen file is created empty
+// with O_EXCL, so concurrent callers, in this process or another, never get
+// the same name. The caller overwrites it with the real content. A cache
+// error in strict mode is returned along with the claimed name.
+func NextAvailableName(dir string, base string, ext string) (_ string, err error) {
+	op := startOp("NextAvailableName", dir)
+	defer op.end(&err)
+	if err := injectedFault("NextAvailableName", dir); err != nil {
+		return "", err
+	}
+
+	dir = cleanPath(dir)
+	nextNameMutex.Lock()
+	defer nextNameMutex.Unlock()
+
+	entries, err := ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		taken[cacheKey(entry.Name)] = true
+	}
+
+	for i := 0; ; i++ {
+		candidate := base + ext
+		if i > 0 {
+			candidate = base + "(" + strconv.Itoa(i) + ")" + ext
+		}
+		if taken[cacheKey(candidate)] {
+			continue
+		}
+
+		name := filepath.Join(dir, candidate)
+		if planned("create", name, "") {
+			return name, nil
+		}
+		ioWait()
+		f, err := os.OpenFile(longPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		ioDone()
+		if os.IsExist(err) {
+			// Created behind the cache's back
+			continue
+		}
+		if err != nil {
+			errorPrinter("NextAvailableName (os.OpenFile): "+err.Error(), name)
+			return "", err
+		}
+		f.Close()
+
+		cacheErr := UpdateFileInfo(name)
+		CacheDelete(cacheKey(dir))
+		publishInvalidation(name)
+		audit("create", name, "", 0)
+		return name, cacheErr // Claimed even when the cache failed
+	}
+}
@@ -0,0 +1,116 @@
+package GMSFS
+
+import (
+	"os"
+	"unsafe"
+)
+
+// DirectIOAlignment is the buffer, offset and length alignment DirectIO
+// needs. 4KB covers the logical block size of current disks.
+const DirectIOAlignment = 4096
+
+// OpenOption adjusts how Open and Create open a file
+type OpenOption func(*openOptions)
+
+type openOptions struct {
+	sync   bool
+	direct bool
+}
+
+// SyncIO opens the file with O_SYNC, every write returns only once the data
+// reached stable storage
+func SyncIO() OpenOption {
+	return func(o *openOptions) { o.sync = true }
+}
+
+// DirectIO bypasses the page cache (O_DIRECT, F_NOCACHE on macOS). Reads
+// from a file returned by Open need buffers from AlignedBuffer. Writes through
+// a file from Create are realigned as needed; a final partial block is
+// written with the page cache, as O_DIRECT cannot write it. Other platforms
+// return ErrNotSupported.
+func DirectIO() OpenOption {
+	return func(o *openOptions) { o.direct = true }
+}
+
+func (o openOptions) flags() int {
+	flag := 0
+	if o.sync {
+		flag |= os.O_SYNC
+	}
+	if o.direct {
+		flag |= directFlag
+	}
+	return flag
+}
+
+func applyOpenOptions(opts []OpenOption) openOptions {
+	var options openOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// openWithOptions opens name and turns on direct I/O where the platform does
+// that after opening
+func openWithOptions(name string, flag int, perm os.FileMode, options openOptions) (*os.File, error) {
+	if options.direct && !directSupported {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrNotSupported}
+	}
+
+	f, err := os.OpenFile(longPath(name), flag|options.flags(), perm)
+	if err != nil {
+		return nil, err
+	}
+	if options.direct {
+		if err := setDirect(f, true); err != nil {
+			f.Close()
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return f, nil
+}
+
+// AlignedBuffer returns a zeroed buffer of size bytes starting on a
+// DirectIOAlignment boundary
+func AlignedBuffer(size int) []byte {
+	b := make([]byte, size+DirectIOAlignment)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(&b[0])) % DirectIOAlignment); rem != 0 {
+		offset = DirectIOAlignment - rem
+	}
+	return b[offset : offset+size : offset+size]
+}
+
+func isAligned(b []byte) bool {
+	return len(b) == 0 || uintptr(unsafe.Pointer(&b[0]))%DirectIOAlignment == 0
+}
+
+// writeDirect writes the aligned head of b with direct I/O and the partial
+// block that remains through the page cache
+func (cf *CachedFile) writeDirect(b []byte) (int, error) {
+	n := 0
+	if head := len(b) - len(b)%DirectIOAlignment; head > 0 {
+		buf := b[:head]
+		if !isAligned(buf) {
+			buf = AlignedBuffer(head)
+			copy(buf, b)
+		}
+		w, err := cf.File.Write(buf)
+		n += w
+		if err != nil {
+			return n, err
+		}
+	}
+	if n == len(b) {
+		return n, nil
+	}
+
+	// The offset is unaligned from here on, keep using the page cache
+	if err := setDirect(cf.File, false); err != nil {
+		return n, err
+	}
+	cf.direct = false
+	w, err := cf.File.Write(b[n:])
+	return n + w, err
+}
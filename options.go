@@ -0,0 +1,58 @@
+package GMSFS
+
+import "github.com/dgraph-io/ristretto"
+
+// Options configures the ristretto cache behind GMSFS
+type Options struct {
+	NumCounters int64 // number of keys to track frequency of
+	MaxCost     int64 // maximum cost of cache, in (approximate) bytes
+	BufferItems int64 // number of keys per Get buffer
+}
+
+var DefaultOptions = Options{
+	NumCounters: 1e7,     // 10M
+	MaxCost:     1 << 30, // 1GB
+	BufferItems: 64,
+}
+
+func newCache(opts Options) (*ristretto.Cache[string, CacheItem], error) {
+	if opts.NumCounters <= 0 {
+		opts.NumCounters = DefaultOptions.NumCounters
+	}
+	if opts.MaxCost <= 0 {
+		opts.MaxCost = DefaultOptions.MaxCost
+	}
+	if opts.BufferItems <= 0 {
+		opts.BufferItems = DefaultOptions.BufferItems
+	}
+
+	return ristretto.NewCache[string, CacheItem](&ristretto.Config[string, CacheItem]{
+		NumCounters: opts.NumCounters,
+		MaxCost:     opts.MaxCost,
+		BufferItems: opts.BufferItems,
+		OnEvict:     onEvict,
+	})
+}
+
+// Configure replaces the cache with one built from opts; zero fields keep
+// their default. All cached entries are dropped, so call it at startup
+// before the cache is in use.
+func Configure(opts Options) error {
+	c, err := newCache(opts)
+	if err != nil {
+		return err
+	}
+
+	old := cache
+	cache = c
+	cacheKeys.Clear()
+	old.Close()
+
+	return nil
+}
+
+// CacheWait blocks until all buffered cache writes have been applied, which
+// makes cache contents deterministic in tests.
+func CacheWait() {
+	cache.Wait()
+}
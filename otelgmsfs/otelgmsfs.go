@@ -0,0 +1,57 @@
+// Package otelgmsfs reports GMSFS filesystem operations as OpenTelemetry
+// spans, annotated with the path, cache hit or miss and bytes transferred.
+//
+//	GMSFS.SetTracer(otelgmsfs.New(nil))
+package otelgmsfs
+
+import (
+	"context"
+
+	"github.com/inpadi/GMSFS"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/inpadi/GMSFS"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// New returns a GMSFS.Tracer creating spans with tp, nil uses the global
+// TracerProvider
+func New(tp trace.TracerProvider) GMSFS.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (t *tracer) Start(ctx context.Context, op string, path string) GMSFS.Span {
+	_, s := t.tracer.Start(ctx, "GMSFS."+op,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("file.path", path)))
+	return &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SetCacheHit(hit bool) {
+	s.span.SetAttributes(attribute.Bool("gmsfs.cache_hit", hit))
+}
+
+func (s *span) SetBytes(n int64) {
+	s.span.SetAttributes(attribute.Int64("gmsfs.bytes", n))
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,156 @@
+package GMSFS
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// whiteoutPrefix marks a file in the upper layer that hides the lower entry
+// with the same name (same convention as aufs and OCI image layers)
+const whiteoutPrefix = ".wh."
+
+// Overlay combines a read-only lower Backend with a writable upper Backend.
+// Reads prefer the upper layer, writes always go to the upper layer and
+// deleting a lower entry leaves a whiteout in the upper layer.
+type Overlay struct {
+	Lower Backend
+	Upper Backend
+}
+
+func NewOverlay(lower, upper Backend) *Overlay {
+	return &Overlay{Lower: lower, Upper: upper}
+}
+
+// dirMaker is implemented by backends that need directories created before
+// files can be written into them
+type dirMaker interface {
+	MkdirAll(name string, perm os.FileMode) error
+}
+
+func (lb *LocalBackend) MkdirAll(name string, perm os.FileMode) error {
+	fullName, err := lb.path(name)
+	if err != nil {
+		return err
+	}
+	return MkdirAll(fullName, perm)
+}
+
+func whiteoutName(name string) string {
+	name = path.Clean("/" + name)
+	return path.Join(path.Dir(name), whiteoutPrefix+path.Base(name))
+}
+
+func (o *Overlay) whitedOut(name string) bool {
+	name = path.Clean("/" + name)
+	for name != "/" {
+		if _, err := o.Upper.Stat(whiteoutName(name)); err == nil {
+			return true
+		}
+		name = path.Dir(name)
+	}
+	return false
+}
+
+func (o *Overlay) Stat(name string) (FileInfo, error) {
+	if info, err := o.Upper.Stat(name); err == nil {
+		return info, nil
+	}
+	if o.whitedOut(name) {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return o.Lower.Stat(name)
+}
+
+func (o *Overlay) ReadDir(name string) ([]FileInfo, error) {
+	upper, upperErr := o.Upper.ReadDir(name)
+
+	var lower []FileInfo
+	lowerErr := fs.ErrNotExist
+	if !o.whitedOut(name) {
+		lower, lowerErr = o.Lower.ReadDir(name)
+	}
+
+	if upperErr != nil && lowerErr != nil {
+		return nil, lowerErr
+	}
+
+	merged := map[string]FileInfo{}
+	hidden := map[string]bool{}
+	for _, entry := range upper {
+		if strings.HasPrefix(entry.Name, whiteoutPrefix) {
+			hidden[strings.TrimPrefix(entry.Name, whiteoutPrefix)] = true
+			continue
+		}
+		merged[entry.Name] = entry
+	}
+	for _, entry := range lower {
+		if _, ok := merged[entry.Name]; ok || hidden[entry.Name] {
+			continue
+		}
+		merged[entry.Name] = entry
+	}
+
+	contents := make([]FileInfo, 0, len(merged))
+	for _, entry := range merged {
+		contents = append(contents, entry)
+	}
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Name < contents[j].Name })
+
+	return contents, nil
+}
+
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	if content, err := o.Upper.ReadFile(name); err == nil {
+		return content, nil
+	}
+	if o.whitedOut(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return o.Lower.ReadFile(name)
+}
+
+func (o *Overlay) WriteFile(name string, content []byte, perm os.FileMode) error {
+	if dm, ok := o.Upper.(dirMaker); ok {
+		if err := dm.MkdirAll(path.Dir(path.Clean("/"+name)), 0755); err != nil {
+			return err
+		}
+	}
+
+	if _, err := o.Upper.Stat(whiteoutName(name)); err == nil {
+		if err := o.Upper.Remove(whiteoutName(name)); err != nil {
+			return err
+		}
+	}
+
+	return o.Upper.WriteFile(name, content, perm)
+}
+
+func (o *Overlay) Remove(name string) error {
+	_, upperErr := o.Upper.Stat(name)
+	_, lowerErr := o.Lower.Stat(name)
+	if upperErr != nil && (lowerErr != nil || o.whitedOut(name)) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if upperErr == nil {
+		if err := o.Upper.Remove(name); err != nil {
+			return err
+		}
+	}
+
+	if lowerErr == nil {
+		if dm, ok := o.Upper.(dirMaker); ok {
+			if err := dm.MkdirAll(path.Dir(path.Clean("/"+name)), 0755); err != nil {
+				return err
+			}
+		}
+		return o.Upper.WriteFile(whiteoutName(name), nil, 0644)
+	}
+
+	return nil
+}
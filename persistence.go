@@ -0,0 +1,99 @@
+package GMSFS
+
+import (
+	"encoding/gob"
+	"os"
+)
+
+type persistedEntry struct {
+	Key        string
+	Info       FileInfo
+	Listed     bool
+	Children   []string
+	ChildModes []os.FileMode
+}
+
+// SaveCache writes all live cache entries to path, so a restarted process can
+// warm up with LoadCache instead of re-statting the whole tree.
+func SaveCache(path string) (err error) {
+	var entries []persistedEntry
+	for key, item := range cacheItems() {
+		entries = append(entries, persistedEntry{
+			Key:        key,
+			Info:       item.Value.(FileInfo),
+			Listed:     item.Listed,
+			Children:   item.Children,
+			ChildModes: item.ChildModes,
+		})
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		errorPrinter("SaveCache (os.Create): "+err.Error(), tmp)
+		return err
+	}
+
+	if err = gob.NewEncoder(f).Encode(entries); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		errorPrinter("SaveCache (gob.Encode): "+err.Error(), "")
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LoadCache restores entries written by SaveCache and returns how many were
+// loaded. Each entry is checked against the filesystem (mtime and size) the
+// first time it is used, and dropped if the file changed in the meantime.
+func LoadCache(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var entries []persistedEntry
+	if err := gob.NewDecoder(f).Decode(&entries); err != nil {
+		errorPrinter("LoadCache (gob.Decode): "+err.Error(), path)
+		return 0, err
+	}
+
+	now := clockNow()
+	for _, entry := range entries {
+		entry.Info.CacheTime = now
+		cacheSet(entry.Key, CacheItem{
+			Value:      entry.Info,
+			Timestamp:  now,
+			Revalidate: true,
+			Listed:     entry.Listed,
+			Children:   entry.Children,
+			ChildModes: entry.ChildModes,
+		})
+	}
+
+	return len(entries), nil
+}
+
+// revalidateEntry reports whether a cached (or restored) entry still matches
+// the disk, comparing mtime and, for files, size. Entries of a CachedBackend
+// are kept.
+func revalidateEntry(key string, info FileInfo) bool {
+	if _, _, remote := backendOf(key); remote {
+		return true
+	}
+	_, stat, err := diskPath(key)
+	if err != nil {
+		return !info.Exists && os.IsNotExist(err)
+	}
+	if !info.Exists || stat.IsDir() != info.IsDir || !stat.ModTime().Equal(info.LastModified) {
+		return false
+	}
+
+	return info.IsDir || stat.Size() == info.Size
+}
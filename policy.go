@@ -0,0 +1,73 @@
+package GMSFS
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachePolicy overrides caching for every path below Prefix, e.g.
+// {Prefix: "/mnt/nfs", TTL: 5 * time.Second} or {Prefix: "/tmp", Disabled: true}
+type CachePolicy struct {
+	Prefix   string
+	TTL      time.Duration // Entry lifetime, 0 uses MaxCacheTime
+	Disabled bool          // Never cache entries below Prefix
+}
+
+var (
+	policyMutex sync.RWMutex
+	policies    []CachePolicy
+)
+
+// SetCachePolicies replaces the active policies. When prefixes overlap the
+// longest matching prefix wins.
+func SetCachePolicies(p ...CachePolicy) {
+	cleaned := make([]CachePolicy, 0, len(p))
+	for _, policy := range p {
+		policy.Prefix = strings.TrimSuffix(cacheKey(policy.Prefix), string(os.PathSeparator))
+		cleaned = append(cleaned, policy)
+	}
+
+	policyMutex.Lock()
+	policies = cleaned
+	policyMutex.Unlock()
+
+	// Drop what the new policies no longer allow to be cached
+	for _, policy := range cleaned {
+		if policy.Disabled {
+			CacheInvalidatePrefix(policy.Prefix)
+		}
+	}
+}
+
+func policyFor(key string) (CachePolicy, bool) {
+	policyMutex.RLock()
+	defer policyMutex.RUnlock()
+
+	var best CachePolicy
+	found := false
+	for _, policy := range policies {
+		if key != policy.Prefix && !strings.HasPrefix(key, policy.Prefix+string(os.PathSeparator)) {
+			continue
+		}
+		if !found || len(policy.Prefix) > len(best.Prefix) {
+			best, found = policy, true
+		}
+	}
+
+	return best, found
+}
+
+// cacheTTL is how long the entry for key may be served from the cache
+func cacheTTL(key string) time.Duration {
+	if policy, ok := policyFor(key); ok && policy.TTL > 0 {
+		return policy.TTL + jitterFor(key)
+	}
+	return MaxCacheTime + jitterFor(key)
+}
+
+func cachingDisabled(key string) bool {
+	policy, ok := policyFor(key)
+	return ok && policy.Disabled
+}
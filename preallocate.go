@@ -0,0 +1,65 @@
+package GMSFS
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotSupported is returned by calls that need a feature this platform or
+// filesystem does not offer
+var ErrNotSupported = errors.New("not supported on this platform")
+
+// Preallocate reserves size bytes of disk space for name without changing its
+// length, so a writer of a large file gets contiguous blocks and runs into
+// ENOSPC up front instead of halfway. The cached entry keeps Size at the
+// written length and records the reservation in Allocated.
+func Preallocate(name string, size int64) (err error) {
+	defer startOp("Preallocate", name).end(&err)
+	if err := injectedFault("Preallocate", name); err != nil {
+		return err
+	}
+	if planned("preallocate", name, "") {
+		return nil
+	}
+	name = cleanPath(name)
+
+	ioWait()
+	f, err := os.OpenFile(longPath(name), os.O_WRONLY, 0)
+	if err != nil {
+		ioDone()
+		errorPrinter("Preallocate (os.OpenFile): "+err.Error(), name)
+		return err
+	}
+	err = preallocate(f, size)
+	stat, serr := f.Stat()
+	f.Close()
+	ioDone()
+
+	if err != nil {
+		if err != ErrNotSupported {
+			errorPrinter("Preallocate (preallocate): "+err.Error(), name)
+		}
+		return &os.PathError{Op: "preallocate", Path: name, Err: err}
+	}
+
+	CacheDelete(cacheKey(name))
+	if serr == nil {
+		info := FileInfo{
+			Exists:       true,
+			Size:         stat.Size(),
+			Mode:         stat.Mode(),
+			LastModified: stat.ModTime(),
+			Name:         filepath.Base(name),
+			CacheTime:    clockNow(),
+			Allocated:    size,
+		}
+		if info.Size > size {
+			info.Allocated = info.Size
+		}
+		CacheAdd(cacheKey(name), info)
+	}
+	publishInvalidation(name)
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE from linux/falloc.h
+const fallocKeepSize = 0x1
+
+func preallocate(f *os.File, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), fallocKeepSize, 0, size)
+	if err == syscall.EOPNOTSUPP {
+		return ErrNotSupported
+	}
+	return err
+}
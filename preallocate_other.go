@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package GMSFS
+
+import "os"
+
+func preallocate(f *os.File, size int64) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,23 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procSetFileInformationByHandle = syscall.NewLazyDLL("kernel32.dll").NewProc("SetFileInformationByHandle")
+
+// fileAllocationInfo is FileAllocationInfo from FILE_INFO_BY_HANDLE_CLASS
+const fileAllocationInfo = 5
+
+// preallocate sets the allocation size, which unlike SetEndOfFile leaves the
+// file length alone
+func preallocate(f *os.File, size int64) error {
+	info := struct{ AllocationSize int64 }{size}
+	r, _, err := procSetFileInformationByHandle.Call(f.Fd(), fileAllocationInfo, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
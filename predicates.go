@@ -0,0 +1,65 @@
+package GMSFS
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsDir reports whether path is a directory (or a symlink to one), answered
+// from the cache when it can. Any error counts as false.
+func IsDir(path string) bool {
+	info, err := Stat(path)
+	return err == nil && info.Exists && info.IsDir
+}
+
+// IsFile reports whether path exists and is not a directory, answered from
+// the cache when it can. Any error counts as false.
+func IsFile(path string) bool {
+	info, err := Stat(path)
+	return err == nil && info.Exists && !info.IsDir
+}
+
+// IsSymlink reports whether path itself is a symbolic link. It always asks
+// the disk: once the cached entry of a link is refreshed it describes the
+// target, and so does the parent's listing, which refers to that entry.
+func IsSymlink(path string) bool {
+	ioWait()
+	stat, err := os.Lstat(longPath(cleanPath(path)))
+	ioDone()
+	return err == nil && stat.Mode()&os.ModeSymlink != 0
+}
+
+// DirExists reports whether path is a directory. A missing path is false
+// without an error; a path that exists as a file is false with ENOTDIR, and
+// other errors of Stat are returned as they are.
+func DirExists(path string) (bool, error) {
+	info, err := Stat(path)
+	if os.IsNotExist(err) || (err == nil && !info.Exists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir {
+		return false, &os.PathError{Op: "stat", Path: path, Err: syscall.ENOTDIR}
+	}
+	return true, nil
+}
+
+// FileExistsStrict reports whether path exists as a file, unlike FileExists
+// which is also true for directories. A missing path is false without an
+// error; a directory is false with EISDIR, and other errors of Stat are
+// returned as they are.
+func FileExistsStrict(path string) (bool, error) {
+	info, err := Stat(path)
+	if os.IsNotExist(err) || (err == nil && !info.Exists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir {
+		return false, &os.PathError{Op: "stat", Path: path, Err: syscall.EISDIR}
+	}
+	return true, nil
+}
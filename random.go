@@ -0,0 +1,99 @@
+package GMSFS
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// RandomFile is a read-write handle for files updated in place. ReadAt,
+// WriteAt and Seek come from the embedded *os.File; once anything was written,
+// Close refreshes the cached size and mtime like CachedFile.Close does.
+type RandomFile struct {
+	*os.File
+	path   string
+	before int64
+	dirty  int32
+}
+
+// OpenRandom opens the existing file name for reading and writing without
+// truncating it
+func OpenRandom(name string) (_ *RandomFile, err error) {
+	defer startOp("OpenRandom", name).end(&err)
+	if err := injectedFault("OpenRandom", name); err != nil {
+		return nil, err
+	}
+	if planned("open", name, "") {
+		return nil, ErrDryRun
+	}
+	name = cleanPath(name)
+	before := auditSize(name)
+
+	ioWait()
+	file, err := os.OpenFile(longPath(name), os.O_RDWR, 0)
+	ioDone()
+	if err != nil {
+		errorPrinter("OpenRandom (os.OpenFile): "+err.Error(), name)
+		return nil, err
+	}
+
+	return &RandomFile{File: file, path: name, before: before}, nil
+}
+
+func (rf *RandomFile) written(err error) error {
+	atomic.StoreInt32(&rf.dirty, 1)
+	return err
+}
+
+func (rf *RandomFile) Write(b []byte) (int, error) {
+	n, err := rf.File.Write(b)
+	return n, rf.written(err)
+}
+
+func (rf *RandomFile) WriteAt(b []byte, off int64) (int, error) {
+	n, err := rf.File.WriteAt(b, off)
+	return n, rf.written(err)
+}
+
+func (rf *RandomFile) WriteString(s string) (int, error) {
+	n, err := rf.File.WriteString(s)
+	return n, rf.written(err)
+}
+
+func (rf *RandomFile) ReadFrom(r io.Reader) (int64, error) {
+	n, err := rf.File.ReadFrom(r)
+	return n, rf.written(err)
+}
+
+func (rf *RandomFile) Truncate(size int64) error {
+	return rf.written(rf.File.Truncate(size))
+}
+
+func (rf *RandomFile) Close() error {
+	if atomic.LoadInt32(&rf.dirty) == 0 {
+		return rf.File.Close()
+	}
+
+	stat, err := rf.File.Stat()
+	if err != nil {
+		errorPrinter("RandomFile.Close (Stat): "+err.Error(), rf.path)
+		rf.File.Close()
+		CacheDelete(cacheKey(rf.path))
+		return err
+	}
+
+	CacheAdd(cacheKey(rf.path), FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		Name:         filepath.Base(rf.path),
+		CacheTime:    clockNow(),
+	})
+	CacheDelete(cacheKey(filepath.Dir(rf.path)))
+	publishInvalidation(rf.path)
+	audit("write", rf.path, "", stat.Size()-rf.before)
+
+	return rf.File.Close()
+}
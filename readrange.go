@@ -0,0 +1,114 @@
+package GMSFS
+
+import (
+	"io"
+	"os"
+)
+
+// ReadFileRange reads length bytes of name starting at offset, a negative
+// length reads to the end of the file. The result is shorter when the file
+// ends first; an offset at or past the end returns io.EOF.
+func ReadFileRange(name string, offset int64, length int64) (_ []byte, err error) {
+	op := startOp("ReadFileRange", name)
+	defer op.end(&err)
+	if err := injectedFault("ReadFileRange", name); err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrInvalid}
+	}
+
+	ioWait()
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		ioDone()
+		errorPrinter("ReadFileRange (os.Open): "+err.Error(), name)
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		ioDone()
+		errorPrinter("ReadFileRange (Stat): "+err.Error(), name)
+		return nil, err
+	}
+	if offset >= stat.Size() {
+		f.Close()
+		ioDone()
+		return nil, io.EOF
+	}
+	if remaining := stat.Size() - offset; length < 0 || length > remaining {
+		length = remaining
+	}
+
+	content := make([]byte, length)
+	n, err := f.ReadAt(content, offset)
+	f.Close()
+	ioDone()
+	if err != nil && err != io.EOF {
+		errorPrinter("ReadFileRange (ReadAt): "+err.Error(), name)
+		return nil, err
+	}
+	op.bytes(int64(n))
+
+	// The file may have shrunk since Stat
+	return content[:n], nil
+}
+
+// ReadFileInto reads name into *buf and returns the number of bytes read, the
+// contents are (*buf)[:n]. *buf is only replaced by a larger one when the
+// file does not fit, sized from the cached file size, so hot loops re-reading
+// small files do not allocate.
+func ReadFileInto(name string, buf *[]byte) (n int, err error) {
+	op := startOp("ReadFileInto", name)
+	defer op.end(&err)
+	if err := injectedFault("ReadFileInto", name); err != nil {
+		return 0, err
+	}
+
+	if size, err := FileSize(name); err == nil && int64(cap(*buf)) < size+1 {
+		// One spare byte, so the EOF is seen without growing again
+		*buf = make([]byte, size+1)
+	}
+	*buf = (*buf)[:cap(*buf)]
+
+	ioWait()
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		ioDone()
+		errorPrinter("ReadFileInto (os.Open): "+err.Error(), name)
+		return 0, err
+	}
+	n, err = readInto(f, buf)
+	f.Close()
+	ioDone()
+	if err != nil {
+		errorPrinter("ReadFileInto (Read): "+err.Error(), name)
+		return n, err
+	}
+	op.bytes(int64(n))
+
+	return n, nil
+}
+
+// readInto reads f to the end into *buf, growing it when the file does not
+// fit
+func readInto(f *os.File, buf *[]byte) (n int, err error) {
+	for {
+		if n == len(*buf) {
+			// The file grew beyond the cached size
+			grown := make([]byte, 2*len(*buf)+512)
+			copy(grown, *buf)
+			*buf = grown
+		}
+
+		r, err := f.Read((*buf)[n:])
+		n += r
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
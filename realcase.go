@@ -0,0 +1,66 @@
+package GMSFS
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// RealCase returns name with every component spelled the way it is stored on
+// disk. Components are matched case insensitively against (cached) directory
+// listings, an exact match wins when a directory holds several spellings.
+func RealCase(name string) (string, error) {
+	name = cleanPath(name)
+
+	volume := filepath.VolumeName(name)
+	rest := name[len(volume):]
+
+	resolved := volume
+	if strings.HasPrefix(rest, string(filepath.Separator)) {
+		resolved += string(filepath.Separator)
+	}
+
+	for _, component := range strings.Split(rest, string(filepath.Separator)) {
+		if component == "" || component == "." {
+			continue
+		}
+		if component == ".." {
+			resolved = filepath.Join(resolved, component)
+			continue
+		}
+
+		dir := resolved
+		if dir == "" || dir == volume {
+			dir = volume + "."
+		}
+		entries, err := ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+
+		match, ok := matchCase(entries, component)
+		if !ok {
+			return "", &fs.PathError{Op: "realcase", Path: name, Err: fs.ErrNotExist}
+		}
+		resolved = filepath.Join(resolved, match)
+	}
+
+	if resolved == "" {
+		resolved = "."
+	}
+	return resolved, nil
+}
+
+func matchCase(entries []FileInfo, component string) (string, bool) {
+	folded := ""
+	want := CanonicalName(component)
+	for _, entry := range entries {
+		if entry.Name == component {
+			return entry.Name, true
+		}
+		if folded == "" && strings.EqualFold(CanonicalName(entry.Name), want) {
+			folded = entry.Name
+		}
+	}
+	return folded, folded != ""
+}
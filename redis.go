@@ -0,0 +1,239 @@
+package GMSFS
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds dialing and every command, so a stalled server fails
+// the publishing call instead of blocking every writer behind it
+const redisTimeout = 5 * time.Second
+
+// RedisBus is an InvalidationBus using Redis PUBLISH/SUBSCRIBE on a single
+// channel. It speaks the RESP protocol directly and reconnects on failure.
+type RedisBus struct {
+	Addr     string
+	Password string
+	Channel  string
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+
+	subMu   sync.Mutex
+	subConn net.Conn
+	closed  chan struct{} // Closed by Close to stop the subscriber
+}
+
+func NewRedisBus(addr, password, channel string) *RedisBus {
+	return &RedisBus{Addr: addr, Password: password, Channel: channel}
+}
+
+func (rb *RedisBus) Publish(msg []byte) error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.conn == nil {
+		conn, rd, err := rb.dial()
+		if err != nil {
+			return err
+		}
+		rb.conn, rb.rd = conn, rd
+	}
+
+	_, err := redisCommand(rb.conn, rb.rd, "PUBLISH", rb.Channel, string(msg))
+	if err != nil {
+		rb.conn.Close()
+		rb.conn = nil
+	}
+	return err
+}
+
+func (rb *RedisBus) Subscribe(handler func(msg []byte)) error {
+	conn, rd, err := rb.dial()
+	if err != nil {
+		return err
+	}
+	closed := make(chan struct{})
+	rb.subMu.Lock()
+	rb.closed = closed
+	rb.subMu.Unlock()
+
+	go func() {
+		for rb.setSubConn(closed, conn) {
+			err := rb.listen(conn, rd, handler)
+			conn.Close()
+			select {
+			case <-closed:
+				return
+			default:
+			}
+			logf("RedisBus (listen): %v", err)
+
+			// Reconnect with a small back-off; invalidations published while
+			// disconnected are lost, the TTL bounds how stale that can leave us
+			for {
+				select {
+				case <-closed:
+					return
+				case <-time.After(time.Second):
+				}
+				conn, rd, err = rb.dial()
+				if err == nil {
+					break
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// setSubConn makes conn the subscriber connection for Close to interrupt,
+// false (and conn closed) when the subscription was closed meanwhile
+func (rb *RedisBus) setSubConn(closed chan struct{}, conn net.Conn) bool {
+	rb.subMu.Lock()
+	defer rb.subMu.Unlock()
+
+	select {
+	case <-closed:
+		conn.Close()
+		return false
+	default:
+	}
+	rb.subConn = conn
+	return true
+}
+
+// Close stops the subscriber and closes both connections. The bus can be
+// subscribed again afterwards.
+func (rb *RedisBus) Close() error {
+	rb.subMu.Lock()
+	if rb.closed != nil {
+		close(rb.closed)
+		rb.closed = nil
+	}
+	if rb.subConn != nil {
+		rb.subConn.Close()
+		rb.subConn = nil
+	}
+	rb.subMu.Unlock()
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.conn != nil {
+		rb.conn.Close()
+		rb.conn, rb.rd = nil, nil
+	}
+	return nil
+}
+
+func (rb *RedisBus) listen(conn net.Conn, rd *bufio.Reader, handler func(msg []byte)) error {
+	if _, err := redisCommand(conn, rd, "SUBSCRIBE", rb.Channel); err != nil {
+		return err
+	}
+	// Messages arrive whenever they are published
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	for {
+		reply, err := redisRead(rd)
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].(string); kind != "message" {
+			continue
+		}
+		if payload, ok := parts[2].(string); ok {
+			handler([]byte(payload))
+		}
+	}
+}
+
+func (rb *RedisBus) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", rb.Addr, redisTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	rd := bufio.NewReader(conn)
+
+	if rb.Password != "" {
+		if _, err := redisCommand(conn, rd, "AUTH", rb.Password); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	return conn, rd, nil
+}
+
+// redisCommand sends a command and reads its reply within redisTimeout
+func redisCommand(conn net.Conn, rd *bufio.Reader, args ...string) (interface{}, error) {
+	if err := conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	cmd := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		cmd += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return nil, err
+	}
+
+	return redisRead(rd)
+}
+
+// redisRead parses one RESP value
+func redisRead(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: short reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = redisRead(rd); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,153 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RemoveAllParallel is RemoveAll for large trees: workers goroutines empty
+// directories concurrently, depth-first, each directory being removed once
+// its children are gone. It keeps going after errors and returns the first.
+func RemoveAllParallel(path string, workers int) (err error) {
+	defer startOp("RemoveAllParallel", path).end(&err)
+	if err := injectedFault("RemoveAllParallel", path); err != nil {
+		return err
+	}
+	if planned("removeall", path, "") {
+		return nil
+	}
+	path = cleanPath(path)
+	if workers < 1 {
+		workers = 1
+	}
+	before := auditSize(path)
+
+	stat, err := os.Lstat(longPath(path))
+	if os.IsNotExist(err) {
+		return removedAll(path)
+	}
+	if err != nil {
+		errorPrinter("RemoveAllParallel (os.Lstat): "+err.Error(), path)
+		return err
+	}
+
+	var errMutex sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		errMutex.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMutex.Unlock()
+	}
+
+	if stat.IsDir() {
+		// A fixed pool of workers emptying directories from a queue, like
+		// Warm. Whoever removes the last child of a directory removes the
+		// directory too, so the tree goes bottom-up.
+		type removeTask struct {
+			dir     string
+			parent  *removeTask
+			pending int // Children left, plus 1 until dir has been emptied
+		}
+		var mutex sync.Mutex
+		wake := sync.NewCond(&mutex)
+		queue := []*removeTask{{dir: path, pending: 1}}
+		busy := 0
+
+		work := func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			for {
+				for len(queue) == 0 && busy > 0 {
+					wake.Wait()
+				}
+				if len(queue) == 0 {
+					// Nothing queued and nobody left to queue more
+					wake.Broadcast()
+					return
+				}
+				task := queue[len(queue)-1]
+				queue = queue[:len(queue)-1]
+				busy++
+
+				mutex.Unlock()
+				subdirs := removeFiles(task.dir, fail)
+				mutex.Lock()
+
+				for _, subdir := range subdirs {
+					queue = append(queue, &removeTask{dir: subdir, parent: task, pending: 1})
+				}
+				task.pending += len(subdirs) - 1
+				for task != nil && task.pending == 0 {
+					mutex.Unlock()
+					ioWait()
+					err := os.Remove(longPath(task.dir))
+					ioDone()
+					mutex.Lock()
+					if err != nil && !os.IsNotExist(err) {
+						fail(err)
+					}
+					if task = task.parent; task != nil {
+						task.pending--
+					}
+				}
+				busy--
+				wake.Broadcast()
+			}
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
+	} else {
+		ioWait()
+		if err := os.Remove(longPath(path)); err != nil && !os.IsNotExist(err) {
+			fail(err)
+		}
+		ioDone()
+	}
+
+	cacheErr := removedAll(path)
+	publishTreeInvalidation(path)
+	if firstErr != nil {
+		errorPrinter("RemoveAllParallel: "+firstErr.Error(), path)
+		return firstErr
+	}
+	audit("removeall", path, "", -before)
+	return cacheErr
+}
+
+// removeFiles removes the entries of dir that are not directories and returns
+// the directories, for RemoveAllParallel to empty next
+func removeFiles(dir string, fail func(error)) []string {
+	ioWait()
+	entries, err := os.ReadDir(longPath(dir))
+	ioDone()
+	if err != nil && !os.IsNotExist(err) {
+		fail(err)
+	}
+
+	var subdirs []string
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			subdirs = append(subdirs, name)
+			continue
+		}
+		ioWait()
+		err := os.Remove(longPath(name))
+		ioDone()
+		if err != nil && !os.IsNotExist(err) {
+			fail(err)
+		}
+	}
+	return subdirs
+}
@@ -0,0 +1,37 @@
+package GMSFS_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inpadi/GMSFS"
+)
+
+func TestRemoveAllParallel(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "tree")
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			files[fmt.Sprintf("d%d/e%d/f", i, j)] = "x"
+			files[fmt.Sprintf("d%d/e%d/g/h", i, j)] = "y"
+		}
+		files[fmt.Sprintf("d%d/f", i)] = "z"
+	}
+	writeTree(t, root, files)
+	// Cached on the way, removing must drop the entries too
+	if _, err := GMSFS.ReadDir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GMSFS.RemoveAllParallel(root, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(root); !os.IsNotExist(err) {
+		t.Errorf("tree left after RemoveAllParallel: %v", err)
+	}
+	if GMSFS.FileExists(root) {
+		t.Errorf("FileExists(%s) after RemoveAllParallel, the cached entry is left", root)
+	}
+}
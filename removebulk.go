@@ -0,0 +1,79 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RemoveGlob removes everything matching pattern (filepath.Match syntax) and
+// returns how many entries were removed. Matching directories are removed
+// with their contents. Each affected directory is rescanned once at the end
+// instead of after every file.
+func RemoveGlob(pattern string) (removed int, err error) {
+	defer startOp("RemoveGlob", pattern).end(&err)
+	if err := injectedFault("RemoveGlob", pattern); err != nil {
+		return 0, err
+	}
+
+	// Match on disk, a stale cached glob would miss new files
+	matches, err := filepath.Glob(cleanPath(pattern))
+	if err != nil {
+		return 0, err
+	}
+
+	byDir := make(map[string][]FileInfo)
+	for _, match := range matches {
+		stat, err := os.Lstat(longPath(match))
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(cleanPath(match))
+		byDir[dir] = append(byDir[dir], FileInfo{Name: stat.Name(), IsDir: stat.IsDir()})
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		n, rerr := removeEntries(dir, byDir[dir])
+		removed += n
+		if rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return removed, err
+}
+
+// RemoveMatching removes the entries of dir for which pred returns true and
+// returns how many were removed, e.g. files older than a cutoff:
+//
+//	RemoveMatching("spool", func(fi FileInfo) bool {
+//		return !fi.IsDir && time.Since(fi.LastModified) > 24*time.Hour
+//	})
+//
+// The entries come from the cached listing and dir is rescanned once at the
+// end. Directories pred accepts are removed with their contents.
+func RemoveMatching(dir string, pred func(FileInfo) bool) (_ int, err error) {
+	defer startOp("RemoveMatching", dir).end(&err)
+	if err := injectedFault("RemoveMatching", dir); err != nil {
+		return 0, err
+	}
+	dir = cleanPath(dir)
+
+	entries, err := ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var matched []FileInfo
+	for _, entry := range entries {
+		if pred(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return removeEntries(dir, matched)
+}
@@ -0,0 +1,156 @@
+package GMSFS
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// ResumeCheckpoint is how many bytes CopyFileResumable copies between
+// progress records
+var ResumeCheckpoint int64 = 64 << 20
+
+// resumeVerify is how much of the copied data is compared with the source
+// before a copy is resumed
+const resumeVerify = 1 << 20
+
+// copyProgress is stored in dst+".part.progress". Size and ModTime identify
+// the source, a changed source starts the copy over.
+type copyProgress struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Offset  int64     `json:"offset"`
+}
+
+// CopyFileResumable copies src to dst through dst+".part", recording its
+// progress every ResumeCheckpoint bytes. Called again after a failure it
+// checks the last copied megabyte against src and continues from the last
+// checkpoint. dst appears only once the copy is complete.
+func CopyFileResumable(src, dst string) (err error) {
+	op := startOp("CopyFileResumable", src)
+	defer op.end(&err)
+	if err := injectedFault("CopyFileResumable", src); err != nil {
+		return err
+	}
+	if planned("copy", src, dst) {
+		return nil
+	}
+	src = cleanPath(src)
+	dst = cleanPath(dst)
+	part := dst + ".part"
+	progressFile := part + ".progress"
+
+	ioWait()
+	in, err := os.Open(longPath(src))
+	ioDone()
+	if err != nil {
+		errorPrinter("CopyFileResumable (os.Open): "+err.Error(), src)
+		return err
+	}
+	defer in.Close()
+
+	si, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	ioWait()
+	out, err := os.OpenFile(longPath(part), os.O_RDWR|os.O_CREATE, 0644)
+	ioDone()
+	if err != nil {
+		errorPrinter("CopyFileResumable (os.OpenFile): "+err.Error(), part)
+		return err
+	}
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+	}()
+
+	progress := copyProgress{Size: si.Size(), ModTime: si.ModTime()}
+	progress.Offset = resumeOffset(in, out, progressFile, progress)
+	if err := out.Truncate(progress.Offset); err != nil {
+		return err
+	}
+
+	for progress.Offset < si.Size() {
+		if _, err := in.Seek(progress.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := out.Seek(progress.Offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		written, err := copyBuffered(out, io.LimitReader(in, ResumeCheckpoint))
+		op.bytes(written)
+		if err == nil {
+			err = out.Sync()
+		}
+		if err != nil {
+			errorPrinter("CopyFileResumable (copyBuffered): "+err.Error(), part)
+			return err
+		}
+		if written == 0 {
+			return io.ErrUnexpectedEOF // src shrank while copying
+		}
+
+		progress.Offset += written
+		record, _ := json.Marshal(progress)
+		if err := os.WriteFile(longPath(progressFile), record, 0644); err != nil {
+			return err
+		}
+	}
+
+	err = out.Close()
+	out = nil
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(longPath(part), si.Mode()); err != nil {
+		return err
+	}
+	if err := Rename(part, dst); err != nil {
+		return err
+	}
+	os.Remove(longPath(progressFile))
+	cacheErr := UpdateFileInfo(dst)
+	audit("copy", src, dst, si.Size())
+
+	return cacheErr
+}
+
+// resumeOffset returns where an interrupted copy can continue, 0 unless the
+// progress record matches the source and the last copied bytes match it too
+func resumeOffset(in *os.File, out *os.File, progressFile string, want copyProgress) int64 {
+	record, err := os.ReadFile(longPath(progressFile))
+	if err != nil {
+		return 0
+	}
+	var progress copyProgress
+	if json.Unmarshal(record, &progress) != nil || progress.Size != want.Size || !progress.ModTime.Equal(want.ModTime) {
+		return 0
+	}
+	if stat, err := out.Stat(); err != nil || stat.Size() < progress.Offset {
+		return 0
+	}
+
+	start := progress.Offset - resumeVerify
+	if start < 0 {
+		start = 0
+	}
+	expected := make([]byte, progress.Offset-start)
+	copied := make([]byte, progress.Offset-start)
+	if _, err := in.ReadAt(expected, start); err != nil {
+		return 0
+	}
+	if _, err := out.ReadAt(copied, start); err != nil {
+		return 0
+	}
+	if !bytes.Equal(expected, copied) {
+		return 0
+	}
+
+	return progress.Offset
+}
@@ -0,0 +1,344 @@
+package GMSFS
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Backend implements Backend for S3 compatible object stores (AWS, MinIO,
+// Ceph RGW, ...). Requests are signed with AWS signature version 4 and use
+// path style addressing. Directories are emulated with "/" delimited prefixes.
+type S3Backend struct {
+	Endpoint     string // e.g. https://s3.eu-west-1.amazonaws.com
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+}
+
+func NewS3Backend(endpoint, region, bucket, accessKey, secretKey string) *S3Backend {
+	return &S3Backend{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    http.DefaultClient,
+	}
+}
+
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+func s3Key(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// PrefixDirs reports that directories are key prefixes, without metadata
+func (sb *S3Backend) PrefixDirs() bool { return true }
+
+func (sb *S3Backend) Stat(name string) (FileInfo, error) {
+	key := s3Key(name)
+	if key == "" {
+		return FileInfo{Exists: true, IsDir: true, Mode: os.ModeDir | 0755, Name: "/"}, nil
+	}
+
+	resp, err := sb.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return FileInfo{
+			Exists:       true,
+			Size:         resp.ContentLength,
+			Mode:         0644,
+			LastModified: lastModified,
+			Name:         path.Base(key),
+		}, nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return FileInfo{}, fmt.Errorf("s3 stat %s: %s", key, resp.Status)
+	}
+
+	// No object, but it may still be a "directory" prefix
+	list, err := sb.list(key+"/", "", 1)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if len(list.Contents) == 0 && len(list.CommonPrefixes) == 0 {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return FileInfo{Exists: true, IsDir: true, Mode: os.ModeDir | 0755, Name: path.Base(key)}, nil
+}
+
+func (sb *S3Backend) ReadDir(name string) ([]FileInfo, error) {
+	prefix := s3Key(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var contents []FileInfo
+	token := ""
+	for {
+		list, err := sb.list(prefix, token, 1000)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range list.CommonPrefixes {
+			contents = append(contents, FileInfo{
+				Exists: true,
+				IsDir:  true,
+				Mode:   os.ModeDir | 0755,
+				Name:   path.Base(strings.TrimSuffix(p.Prefix, "/")),
+			})
+		}
+		for _, obj := range list.Contents {
+			if obj.Key == prefix {
+				continue // directory marker object
+			}
+			contents = append(contents, FileInfo{
+				Exists:       true,
+				Size:         obj.Size,
+				Mode:         0644,
+				LastModified: obj.LastModified,
+				Name:         path.Base(obj.Key),
+			})
+		}
+
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			break
+		}
+		token = list.NextContinuationToken
+	}
+
+	if contents == nil && prefix != "" {
+		if _, err := sb.Stat(name); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Name < contents[j].Name })
+	return contents, nil
+}
+
+func (sb *S3Backend) ReadFile(name string) ([]byte, error) {
+	key := s3Key(name)
+	resp, err := sb.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := s3StatusError("get", name, resp); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (sb *S3Backend) WriteFile(name string, content []byte, perm os.FileMode) error {
+	resp, err := sb.do(http.MethodPut, s3Key(name), nil, content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return s3StatusError("put", name, resp)
+}
+
+func (sb *S3Backend) Remove(name string) error {
+	resp, err := sb.do(http.MethodDelete, s3Key(name), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return s3StatusError("delete", name, resp)
+}
+
+func (sb *S3Backend) list(prefix string, token string, maxKeys int) (s3ListResult, error) {
+	var result s3ListResult
+
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("delimiter", "/")
+	query.Set("prefix", prefix)
+	query.Set("max-keys", fmt.Sprint(maxKeys))
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+
+	resp, err := sb.do(http.MethodGet, "", query, nil)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if err := s3StatusError("list", prefix, resp); err != nil {
+		return result, err
+	}
+
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
+func s3StatusError(op string, name string, resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case resp.StatusCode == http.StatusForbidden:
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s: %s %s", op, name, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// do sends a signed request for key (empty for bucket level requests)
+func (sb *S3Backend) do(method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	uri := "/" + s3Escape(sb.Bucket, false)
+	if key != "" {
+		uri += "/" + s3Escape(key, false)
+	}
+
+	endpoint, err := url.Parse(sb.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	rawQuery := s3CanonicalQuery(query)
+	req, err := http.NewRequest(method, sb.Endpoint+uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = rawQuery
+	req.ContentLength = int64(len(body))
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", endpoint.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if sb.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sb.SessionToken)
+	}
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if sb.SessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = endpoint.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		uri,
+		rawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := now.Format("20060102") + "/" + sb.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+sb.SecretKey), now.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, sb.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+sb.AccessKey+"/"+scope+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+
+	client := sb.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape URI encodes s as required by signature version 4
+func s3Escape(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,142 @@
+package GMSFS
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Scanner walks a tree in a fixed order, directories before their entries
+// and entries sorted by name, and can be stopped and resumed from a
+// checkpoint token. Multi-hour scans of large volumes continue after a
+// restart instead of starting over.
+//
+// A Scanner is not safe for concurrent use.
+type Scanner struct {
+	root  string
+	stack []scanFrame
+	last  string // Path of the last entry returned, relative to root
+}
+
+type scanFrame struct {
+	rel     string
+	entries []FileInfo
+	next    int
+}
+
+// scanCheckpoint is the content of a checkpoint token
+type scanCheckpoint struct {
+	Root  string `json:"root"`
+	After string `json:"after"`
+}
+
+// NewScanner returns a Scanner over everything below root
+func NewScanner(root string) (*Scanner, error) {
+	return ResumeScanner(root, "")
+}
+
+// ResumeScanner returns a Scanner that continues after the position of
+// token, as returned by Checkpoint. An empty token starts at the beginning.
+// Entries created behind the checkpoint since are not visited; if the
+// checkpointed entry was removed, the scan continues with the next one.
+func ResumeScanner(root string, token string) (*Scanner, error) {
+	root = cleanPath(root)
+	s := &Scanner{root: root}
+
+	after := ""
+	if token != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan checkpoint: %w", err)
+		}
+		var cp scanCheckpoint
+		if err := json.Unmarshal(raw, &cp); err != nil {
+			return nil, fmt.Errorf("invalid scan checkpoint: %w", err)
+		}
+		if cleanPath(cp.Root) != root {
+			return nil, fmt.Errorf("scan checkpoint is for %s, not %s", cp.Root, root)
+		}
+		after = filepath.FromSlash(cp.After)
+	}
+
+	if err := s.push(""); err != nil {
+		return nil, err
+	}
+	if after == "" {
+		return s, nil
+	}
+
+	// Rebuild the stack down to the checkpointed entry
+	s.last = after
+	components := strings.Split(after, string(filepath.Separator))
+	for i, name := range components {
+		frame := &s.stack[len(s.stack)-1]
+		for frame.next < len(frame.entries) && frame.entries[frame.next].Name < name {
+			frame.next++
+		}
+		if frame.next == len(frame.entries) || frame.entries[frame.next].Name != name {
+			// Gone since the checkpoint, carry on with what follows it
+			break
+		}
+
+		entry := frame.entries[frame.next]
+		frame.next++
+		if !entry.IsDir {
+			break
+		}
+		// The directory itself was returned, its entries come next (or are
+		// partly done, for a parent of the checkpoint)
+		if err := s.push(filepath.Join(components[:i+1]...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Scanner) push(rel string) error {
+	entries, err := ReadDir(filepath.Join(s.root, rel))
+	if err != nil {
+		return err
+	}
+	s.stack = append(s.stack, scanFrame{rel: rel, entries: entries})
+	return nil
+}
+
+// Next returns the path and FileInfo of the next entry, io.EOF after the
+// last one. Directories that cannot be read are skipped.
+func (s *Scanner) Next() (string, FileInfo, error) {
+	for len(s.stack) > 0 {
+		frame := &s.stack[len(s.stack)-1]
+		if frame.next == len(frame.entries) {
+			s.stack = s.stack[:len(s.stack)-1]
+			continue
+		}
+
+		entry := frame.entries[frame.next]
+		frame.next++
+		entry.Contents = nil
+		rel := filepath.Join(frame.rel, entry.Name)
+		s.last = rel
+
+		if entry.IsDir {
+			if err := s.push(rel); err != nil {
+				errorPrinter("Scanner (ReadDir): "+err.Error(), filepath.Join(s.root, rel))
+			}
+		}
+		return filepath.Join(s.root, rel), entry, nil
+	}
+
+	return "", FileInfo{}, io.EOF
+}
+
+// Checkpoint returns a token for the current position, to be persisted and
+// passed to ResumeScanner. The entry last returned by Next is not returned
+// again after resuming.
+func (s *Scanner) Checkpoint() string {
+	raw, _ := json.Marshal(scanCheckpoint{Root: s.root, After: filepath.ToSlash(s.last)})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
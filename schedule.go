@@ -0,0 +1,124 @@
+package GMSFS
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Job is a maintenance task run by a Scheduler. ctx is cancelled when the
+// scheduler shuts down, long jobs should stop early then.
+type Job func(ctx context.Context) error
+
+// ErrSchedulerStopped is returned by Schedule after Shutdown
+var ErrSchedulerStopped = errors.New("scheduler is shut down")
+
+// Scheduler runs maintenance jobs at fixed intervals, so services using
+// GMSFS share one set of tickers and stop them together. Failed runs are
+// logged and reported to SubscribeErrors.
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+	wg     sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{ctx: ctx, cancel: cancel}
+}
+
+// Schedule runs job every interval, the first time one interval from now. A
+// run that takes longer than interval delays the next one, a job never runs
+// twice at the same time.
+func (s *Scheduler) Schedule(interval time.Duration, job Job) error {
+	if interval <= 0 {
+		return errors.New("schedule interval must be positive")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ctx.Err() != nil {
+		return ErrSchedulerStopped
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := job(s.ctx); err != nil && s.ctx.Err() == nil {
+				logf("GMSFS scheduled job: %v", err)
+				publishError(ErrorEvent{Op: "Schedule", Err: err})
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown stops scheduling, cancels the context of running jobs and waits
+// for them to return, or for ctx to be done
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.cancel()
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RetentionJob removes the files directly in dir last modified more than
+// maxAge ago
+func RetentionJob(dir string, maxAge time.Duration) Job {
+	return func(ctx context.Context) error {
+		_, err := RemoveMatching(dir, func(info FileInfo) bool {
+			return !info.IsDir && clockSince(info.LastModified) > maxAge
+		})
+		return err
+	}
+}
+
+// PruneEmptyDirsJob removes the empty directories below root, see
+// PruneEmptyDirs
+func PruneEmptyDirsJob(root string) Job {
+	return func(ctx context.Context) error {
+		_, err := PruneEmptyDirs(root)
+		return err
+	}
+}
+
+// CacheSweepJob drops the expired cache entries, like StartSweeper
+func CacheSweepJob() Job {
+	return func(ctx context.Context) error {
+		CacheSweep()
+		return nil
+	}
+}
+
+// ManifestJob rewrites the manifest of root at manifestPath, see
+// WriteManifest
+func ManifestJob(root string, manifestPath string) Job {
+	return func(ctx context.Context) error {
+		return WriteManifest(root, manifestPath)
+	}
+}
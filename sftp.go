@@ -0,0 +1,111 @@
+package GMSFS
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// SFTPClient is the subset of an SFTP client used by SFTPBackend. A
+// *sftp.Client from github.com/pkg/sftp satisfies it once Open and Create
+// are wrapped to return io.ReadCloser / io.WriteCloser.
+type SFTPClient interface {
+	Stat(p string) (os.FileInfo, error)
+	ReadDir(p string) ([]os.FileInfo, error)
+	Open(p string) (io.ReadCloser, error)
+	Create(p string) (io.WriteCloser, error)
+	Chmod(p string, mode os.FileMode) error
+	Remove(p string) error
+}
+
+// SFTPBackend implements Backend on top of an SFTP session. Wrap it in a
+// CachedBackend to avoid a round trip for every Stat and ReadDir.
+type SFTPBackend struct {
+	Client SFTPClient
+	Root   string
+}
+
+func NewSFTPBackend(client SFTPClient, root string) *SFTPBackend {
+	return &SFTPBackend{Client: client, Root: root}
+}
+
+func (sb *SFTPBackend) path(name string) string {
+	return path.Join(sb.Root, path.Clean("/"+name))
+}
+
+func fileInfoFromOS(stat os.FileInfo) FileInfo {
+	return FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		IsDir:        stat.IsDir(),
+		Name:         stat.Name(),
+	}
+}
+
+func (sb *SFTPBackend) Stat(name string) (FileInfo, error) {
+	stat, err := sb.Client.Stat(sb.path(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	return fileInfoFromOS(stat), nil
+}
+
+func (sb *SFTPBackend) ReadDir(name string) ([]FileInfo, error) {
+	entries, err := sb.Client.ReadDir(sb.path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		contents = append(contents, fileInfoFromOS(entry))
+	}
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Name < contents[j].Name })
+
+	return contents, nil
+}
+
+func (sb *SFTPBackend) ReadFile(name string) ([]byte, error) {
+	f, err := sb.Client.Open(sb.path(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// WriteFile applies perm only when it creates the file, like os.WriteFile an
+// existing file keeps its mode
+func (sb *SFTPBackend) WriteFile(name string, content []byte, perm os.FileMode) (err error) {
+	_, statErr := sb.Client.Stat(sb.path(name))
+	created := errors.Is(statErr, os.ErrNotExist)
+
+	f, err := sb.Client.Create(sb.path(name))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if _, err = f.Write(content); err != nil {
+		return err
+	}
+
+	if !created {
+		return nil
+	}
+	return sb.Client.Chmod(sb.path(name), perm)
+}
+
+func (sb *SFTPBackend) Remove(name string) error {
+	return sb.Client.Remove(sb.path(name))
+}
@@ -0,0 +1,74 @@
+package GMSFS
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Shred overwrites the contents of name passes times with random data, syncing
+// after each pass, renames it to a random name and removes it. Its cache
+// entries, including the cached checksum, are dropped.
+//
+// Overwriting in place only reaches the original blocks on filesystems that
+// update in place. On SSDs (wear levelling), copy-on-write filesystems (btrfs,
+// ZFS, APFS), snapshots and journaling of data, old copies may survive; use
+// full-disk encryption for data that must not be recoverable there.
+func Shred(name string, passes int) (err error) {
+	op := startOp("Shred", name)
+	defer op.end(&err)
+	if err := injectedFault("Shred", name); err != nil {
+		return err
+	}
+	if planned("shred", name, "") {
+		return nil
+	}
+	if passes < 1 {
+		passes = 1
+	}
+	name = cleanPath(name)
+
+	ioWait()
+	f, err := os.OpenFile(longPath(name), os.O_WRONLY, 0)
+	ioDone()
+	if err != nil {
+		errorPrinter("Shred (os.OpenFile): "+err.Error(), name)
+		return err
+	}
+
+	stat, err := f.Stat()
+	if err == nil && !stat.Mode().IsRegular() {
+		err = fmt.Errorf("%s is not a regular file", name)
+	}
+	for pass := 0; pass < passes && err == nil; pass++ {
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			break
+		}
+		var written int64
+		written, err = copyBuffered(f, io.LimitReader(rand.Reader, stat.Size()))
+		op.bytes(written)
+		if err == nil {
+			err = f.Sync()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	CacheDelete(cacheKey(name))
+	if err != nil {
+		errorPrinter("Shred: "+err.Error(), name)
+		return err
+	}
+
+	// Hide the old name from the directory before unlinking
+	b := make([]byte, 8)
+	rand.Read(b)
+	hidden := filepath.Join(filepath.Dir(name), ".gmsfs-shred-"+hex.EncodeToString(b))
+	if err := Rename(name, hidden); err != nil {
+		return err
+	}
+	return Remove(hidden)
+}
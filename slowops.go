@@ -0,0 +1,60 @@
+package GMSFS
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var slowOpThreshold int64
+
+// SetSlowOpThreshold logs every filesystem call taking longer than threshold,
+// with the operation, path and elapsed time. 0 turns it off.
+func SetSlowOpThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowOpThreshold, int64(threshold))
+}
+
+// opTimer follows one call through slow-op logging and tracing
+type opTimer struct {
+	op    string
+	path  string
+	start time.Time
+	span  Span
+}
+
+// startOp times a call, use as defer startOp("Stat", name).end(&err)
+func startOp(op string, name string) *opTimer {
+	return startOpContext(context.Background(), op, name)
+}
+
+func startOpContext(ctx context.Context, op string, name string) *opTimer {
+	t := &opTimer{op: op, path: name, start: time.Now()}
+	if tracer := currentTracer(); tracer != nil {
+		t.span = tracer.Start(ctx, op, name)
+	}
+	return t
+}
+
+func (t *opTimer) cacheHit(hit bool) {
+	if t.span != nil {
+		t.span.SetCacheHit(hit)
+	}
+}
+
+func (t *opTimer) bytes(n int64) {
+	if t.span != nil {
+		t.span.SetBytes(n)
+	}
+}
+
+func (t *opTimer) end(err *error) {
+	elapsed := time.Since(t.start)
+	recordLatency(t.op, mountOf(t.path), elapsed)
+	if threshold := time.Duration(atomic.LoadInt64(&slowOpThreshold)); threshold > 0 && elapsed > threshold {
+		logf("GMSFS slow %s %s: %v", t.op, t.path, elapsed)
+	}
+	if t.span != nil {
+		t.span.End(*err)
+	}
+	publishOpError(t.op, t.path, *err)
+}
@@ -0,0 +1,76 @@
+package GMSFS
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// TreeSnapshot is the metadata of a subtree at one moment, keyed by the path
+// relative to Root
+type TreeSnapshot struct {
+	Root    string
+	Taken   time.Time
+	Entries map[string]FileInfo
+}
+
+// Snapshot records the metadata of everything below root from the cached
+// listings, reading from disk only what is not cached. Comparing two
+// snapshots (or one with the live tree) detects changes by size, mtime and
+// type without hashing any content.
+func Snapshot(root string) (_ *TreeSnapshot, err error) {
+	defer startOp("Snapshot", root).end(&err)
+	if err := injectedFault("Snapshot", root); err != nil {
+		return nil, err
+	}
+	root = cleanPath(root)
+
+	snap := &TreeSnapshot{Root: root, Taken: clockNow(), Entries: map[string]FileInfo{}}
+	var walk func(dir string, rel string) error
+	walk = func(dir string, rel string) error {
+		entries, err := ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entry.Contents = nil
+			entryRel := filepath.Join(rel, entry.Name)
+			snap.Entries[entryRel] = entry
+			if entry.IsDir {
+				if err := walk(filepath.Join(dir, entry.Name), entryRel); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root, ""); err != nil {
+		errorPrinter("Snapshot (ReadDir): "+err.Error(), root)
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Diff returns what changed from s to the later snapshot, as WatchEvents with
+// paths relative to the root, sorted by path
+func (s *TreeSnapshot) Diff(later *TreeSnapshot) []WatchEvent {
+	changes := diffListings("", s.Entries, later.Entries)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// DiffLive compares s with the tree as it is on disk now, bypassing the cache
+// so changes made outside GMSFS are seen too
+func (s *TreeSnapshot) DiffLive() ([]WatchEvent, error) {
+	live, err := scanTree(s.Root)
+	if err != nil {
+		return nil, err
+	}
+	return s.Diff(&TreeSnapshot{Root: s.Root, Taken: clockNow(), Entries: live}), nil
+}
+
+// Paths returns the paths of the snapshot in sorted order
+func (s *TreeSnapshot) Paths() []string {
+	return sortedKeys(s.Entries)
+}
@@ -0,0 +1,155 @@
+package GMSFS
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrSpoolEmpty is returned by Claim when there is nothing to process
+var ErrSpoolEmpty = errors.New("spool is empty")
+
+// Spool is a directory based work queue that several processes may share.
+// Items are files in incoming/, a claimed item is moved to processing/ until
+// it is acknowledged (removed) or rejected (moved back to incoming/).
+type Spool struct {
+	Dir string
+}
+
+// SpoolItem is an item claimed from a Spool, Path is its file in processing/
+type SpoolItem struct {
+	Name string
+	Path string
+}
+
+// NewSpool opens the spool in dir, creating its directories when needed
+func NewSpool(dir string) (*Spool, error) {
+	s := &Spool{Dir: cleanPath(dir)}
+	for _, sub := range []string{s.incoming(), s.processing()} {
+		if err := MkdirAll(sub, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Spool) incoming() string   { return filepath.Join(s.Dir, "incoming") }
+func (s *Spool) processing() string { return filepath.Join(s.Dir, "processing") }
+
+// Enqueue adds data as a new item and returns its name. The item only
+// appears in incoming/ once it is completely written.
+func (s *Spool) Enqueue(data []byte) (_ string, err error) {
+	defer startOp("Spool.Enqueue", s.incoming()).end(&err)
+	if err := injectedFault("Spool.Enqueue", s.incoming()); err != nil {
+		return "", err
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
+	final := filepath.Join(s.incoming(), name)
+	if planned("write", final, "") {
+		return name, nil
+	}
+
+	ioWait()
+	tmpName, err := writeSpoolTemp(s.incoming(), data)
+	if err == nil {
+		if err = os.Rename(longPath(tmpName), longPath(final)); err != nil {
+			os.Remove(longPath(tmpName))
+		}
+	}
+	ioDone()
+	if err != nil {
+		errorPrinter("Spool.Enqueue: "+err.Error(), s.incoming())
+		return "", err
+	}
+
+	cacheErr := renamed(tmpName, final)
+	audit("write", final, "", int64(len(data)))
+	return name, cacheErr
+}
+
+// writeSpoolTemp writes data to a new hidden file in dir, Claim skips it
+func writeSpoolTemp(dir string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(longPath(dir), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpName := filepath.Join(dir, filepath.Base(tmp.Name()))
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmpName, nil
+}
+
+// Claim moves the oldest item from incoming/ to processing/ and returns it.
+// Renaming is atomic, so an item is only ever claimed by one caller.
+func (s *Spool) Claim() (_ SpoolItem, err error) {
+	defer startOp("Spool.Claim", s.incoming()).end(&err)
+	if err := injectedFault("Spool.Claim", s.incoming()); err != nil {
+		return SpoolItem{}, err
+	}
+
+	// Read from disk, other processes add and claim items behind our cache
+	ioWait()
+	entries, err := os.ReadDir(longPath(s.incoming()))
+	ioDone()
+	if err != nil {
+		return SpoolItem{}, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		src := filepath.Join(s.incoming(), name)
+		dst := filepath.Join(s.processing(), name)
+		if planned("rename", src, dst) {
+			return SpoolItem{Name: name, Path: dst}, nil
+		}
+		ioWait()
+		err := os.Rename(longPath(src), longPath(dst))
+		ioDone()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // Claimed by someone else
+			}
+			return SpoolItem{}, err
+		}
+		cacheErr := renamed(src, dst)
+		audit("rename", src, dst, 0)
+
+		return SpoolItem{Name: name, Path: dst}, cacheErr
+	}
+
+	return SpoolItem{}, ErrSpoolEmpty
+}
+
+// Ack removes a processed item
+func (s *Spool) Ack(item SpoolItem) error {
+	return Remove(filepath.Join(s.processing(), item.Name))
+}
+
+// Nack returns an item to incoming/ so it is claimed again
+func (s *Spool) Nack(item SpoolItem) error {
+	return Rename(filepath.Join(s.processing(), item.Name), filepath.Join(s.incoming(), item.Name))
+}
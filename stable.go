@@ -0,0 +1,49 @@
+package GMSFS
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WaitForStable returns once the size and modification time of name have not
+// changed for quiet, so a file that is still being written is not read half
+// way. On Windows it also returns as soon as the file can be opened
+// exclusively, as that means no writer has it open anymore.
+func WaitForStable(ctx context.Context, name string, quiet time.Duration) error {
+	stat, err := os.Stat(longPath(name))
+	if err != nil {
+		return err
+	}
+	size, modTime := stat.Size(), stat.ModTime()
+	since := time.Now()
+
+	interval := WaitPollInterval
+	if quiet > 0 && quiet < interval {
+		interval = quiet
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if time.Since(since) >= quiet || exclusiveOpen(name) {
+			UpdateFileInfo(name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		stat, err := os.Stat(longPath(name))
+		if err != nil {
+			return err
+		}
+		if stat.Size() != size || !stat.ModTime().Equal(modTime) {
+			size, modTime = stat.Size(), stat.ModTime()
+			since = time.Now()
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package GMSFS
+
+// exclusiveOpen is not meaningful without mandatory share modes, only the
+// quiet period decides there
+func exclusiveOpen(name string) bool {
+	return false
+}
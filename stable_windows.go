@@ -0,0 +1,19 @@
+package GMSFS
+
+import "syscall"
+
+// exclusiveOpen reports whether name can be opened without sharing, which
+// fails while any other process still has the file open
+func exclusiveOpen(name string) bool {
+	p, err := syscall.UTF16PtrFromString(longPath(name))
+	if err != nil {
+		return false
+	}
+
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return false
+	}
+	syscall.CloseHandle(h)
+	return true
+}
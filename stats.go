@@ -0,0 +1,69 @@
+package GMSFS
+
+import "sync/atomic"
+
+// CacheStatistics summarizes the current content of the cache
+type CacheStatistics struct {
+	Entries  int
+	Files    int
+	Dirs     int
+	Negative int   // Entries recording that a path does not exist
+	Bytes    int64 // Sum of the cached file sizes
+	Hits     uint64
+	Misses   uint64
+	Latency  []OpLatency // Per operation and mount, see SetMounts
+}
+
+func CacheStats() CacheStatistics {
+	stats := CacheStatistics{
+		Hits:    atomic.LoadUint64(&cacheHits),
+		Misses:  atomic.LoadUint64(&cacheMisses),
+		Latency: Latencies(),
+	}
+
+	for _, info := range CacheEntries() {
+		stats.Entries++
+		switch {
+		case !info.Exists:
+			stats.Negative++
+		case info.IsDir:
+			stats.Dirs++
+		default:
+			stats.Files++
+			stats.Bytes += info.Size
+		}
+	}
+
+	return stats
+}
+
+// CacheEntries returns a copy of every entry currently in the cache, keyed by
+// cache key. Expiry is not checked, so the result shows exactly what is cached.
+func CacheEntries() map[string]FileInfo {
+	entries := map[string]FileInfo{}
+	for key, item := range cacheItems() {
+		value := item.Value.(FileInfo)
+		if item.Listed {
+			value = resolveChildren(item, value)
+		}
+		entries[key] = value
+	}
+
+	return entries
+}
+
+// cacheItems reads every indexed key. A key that is not found is left in the
+// index: it may be a set still buffered by ristretto, evicted entries are
+// removed from the index by onEvict.
+func cacheItems() map[string]CacheItem {
+	items := map[string]CacheItem{}
+	for _, key := range cacheKeys.Keys() {
+		item, found := cache.Get(key)
+		if !found {
+			continue
+		}
+		items[key] = item
+	}
+
+	return items
+}
@@ -0,0 +1,102 @@
+package GMSFS
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFrom streams r into name, creating or truncating it, and caches the
+// final size and mtime. Use it instead of WriteFile when the content does not
+// fit in memory.
+func WriteFrom(name string, r io.Reader, perm os.FileMode) (written int64, err error) {
+	op := startOp("WriteFrom", name)
+	defer op.end(&err)
+	if err := injectedFault("WriteFrom", name); err != nil {
+		return 0, err
+	}
+	if planned("write", name, "") {
+		return 0, nil
+	}
+	name = cleanPath(name)
+	lowerCaseName := cacheKey(name)
+	before := auditSize(name)
+
+	ioWait()
+	f, err := os.OpenFile(longPath(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	ioDone()
+	if err != nil {
+		errorPrinter("WriteFrom (os.OpenFile): "+err.Error(), name)
+		return 0, err
+	}
+
+	var h hash.Hash
+	if checksumOnWriteEnabled() {
+		h = sha256.New()
+		r = io.TeeReader(r, h)
+	}
+	written, err = copyBuffered(f, r)
+	op.bytes(written)
+	if err == nil && syncOnClose() {
+		err = f.Sync()
+	}
+	var stat os.FileInfo
+	if err == nil {
+		stat, err = f.Stat()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	CacheDelete(cacheKey(filepath.Dir(name)))
+	publishInvalidation(name)
+	if err != nil {
+		CacheDelete(lowerCaseName)
+		errorPrinter("WriteFrom: "+err.Error(), name)
+		return written, err
+	}
+
+	info := FileInfo{
+		Exists:       true,
+		Size:         stat.Size(),
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		Name:         filepath.Base(name),
+		CacheTime:    clockNow(),
+	}
+	if h != nil {
+		info.SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+	CacheAdd(lowerCaseName, info)
+	audit("write", name, "", written-before)
+
+	return written, nil
+}
+
+// ReadTo streams the contents of name into w
+func ReadTo(name string, w io.Writer) (read int64, err error) {
+	op := startOp("ReadTo", name)
+	defer op.end(&err)
+	if err := injectedFault("ReadTo", name); err != nil {
+		return 0, err
+	}
+
+	ioWait()
+	f, err := os.Open(longPath(name))
+	ioDone()
+	if err != nil {
+		errorPrinter("ReadTo (os.Open): "+err.Error(), name)
+		return 0, err
+	}
+	defer f.Close()
+
+	read, err = copyBuffered(w, f)
+	op.bytes(read)
+	if err != nil {
+		errorPrinter("ReadTo: "+err.Error(), name)
+	}
+	return read, err
+}
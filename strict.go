@@ -0,0 +1,84 @@
+package GMSFS
+
+import "sync/atomic"
+
+// CacheError is a failed refresh of the cache after the change on disk was
+// made; the cached entries for Path may be stale until they are refreshed or
+// expire
+type CacheError struct {
+	Op   string // UpdateFileInfo or UpdateDirectoryContents
+	Path string
+	Err  error
+}
+
+func (e *CacheError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *CacheError) Unwrap() error {
+	return e.Err
+}
+
+type cacheErrorHolder struct{ handler func(*CacheError) }
+
+var (
+	strictMode        int32
+	cacheErrorHandler atomic.Value
+)
+
+func init() {
+	cacheErrorHandler.Store(cacheErrorHolder{})
+}
+
+// SetStrictMode makes UpdateFileInfo and UpdateDirectoryContents return their
+// errors, and the operations calling them fail with a *CacheError even
+// though the change on disk was made. In relaxed mode, the default, the
+// errors are logged, passed to the SetCacheErrorHandler handler and the
+// operations succeed. GMSFS has one cache per process, so the mode is
+// process-wide.
+func SetStrictMode(strict bool) {
+	var v int32
+	if strict {
+		v = 1
+	}
+	atomic.StoreInt32(&strictMode, v)
+}
+
+// StrictMode reports whether SetStrictMode is on
+func StrictMode() bool {
+	return atomic.LoadInt32(&strictMode) != 0
+}
+
+// SetCacheErrorHandler calls handler with each cache refresh that failed in
+// relaxed mode, nil removes it. The handler runs on the goroutine of the
+// failed operation and should not block.
+func SetCacheErrorHandler(handler func(*CacheError)) {
+	cacheErrorHandler.Store(cacheErrorHolder{handler})
+}
+
+// cacheFailed handles a failed cache refresh by the mode: the error to
+// return in strict mode, nil after reporting it in relaxed mode
+func cacheFailed(op string, path string, err error) error {
+	cerr := &CacheError{Op: op, Path: path, Err: err}
+	logf("%v", cerr)
+	// The failed refreshes dropped the entries they could not refresh
+	publishError(ErrorEvent{Op: op, Path: path, Err: err, Cache: true, Repaired: true})
+	if StrictMode() {
+		return cerr
+	}
+	if handler := cacheErrorHandler.Load().(cacheErrorHolder).handler; handler != nil {
+		handler(cerr)
+	}
+	return nil
+}
+
+// firstError returns the first of errs that is not nil, so an operation can
+// finish its cache maintenance before it reports a failure
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
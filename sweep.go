@@ -0,0 +1,44 @@
+package GMSFS
+
+import (
+	"sync"
+	"time"
+)
+
+// CacheSweep drops every expired entry and returns how many were removed.
+// Negative entries expire after NegativeCacheTime, others after MaxCacheTime or
+// their CachePolicy TTL (plus MaxStaleTime when stale-while-revalidate is enabled).
+func CacheSweep() int {
+	removed := 0
+	for key, info := range CacheEntries() {
+		age := clockSince(info.CacheTime)
+		if (!info.Exists && age > NegativeCacheTime) || age > cacheTTL(key)+MaxStaleTime {
+			CacheDelete(key)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// StartSweeper runs CacheSweep every interval until the returned stop
+// function is called.
+func StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				CacheSweep()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,32 @@
+package GMSFS
+
+import (
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+// revalidating holds the keys with a background refresh in flight
+var revalidating = cmap.New[struct{}]()
+
+// revalidateInBackground refreshes a stale entry once, however many callers
+// are served the stale value in the meantime
+func revalidateInBackground(key string, value FileInfo) {
+	if !revalidating.SetIfAbsent(key, struct{}{}) {
+		return
+	}
+
+	go func() {
+		defer revalidating.Remove(key)
+
+		// Stagger refreshes of entries that went stale together
+		time.Sleep(jitterFor(key) / 2)
+
+		if cb, name, remote := backendOf(key); remote {
+			cb.refresh(name, value.IsDir)
+			return
+		}
+		name, _, _ := diskPath(key)
+		UpdateFileInfo(name)
+	}()
+}
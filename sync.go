@@ -0,0 +1,85 @@
+package GMSFS
+
+import (
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// SyncPolicy selects when writes are flushed to stable storage with fsync
+type SyncPolicy int32
+
+const (
+	SyncCopies     SyncPolicy = iota // Only CopyFile syncs (default, the historic behaviour)
+	SyncNever                        // Leave flushing to the OS
+	SyncOnClose                      // Sync once a file is fully written, before it is closed
+	SyncEveryWrite                   // Also sync after every Write of a file from Create
+)
+
+var syncPolicy int32
+
+// SetSyncPolicy sets the durability policy of WriteFile, WriteFrom, Append,
+// CopyFile and the files returned by Create
+func SetSyncPolicy(policy SyncPolicy) {
+	atomic.StoreInt32(&syncPolicy, int32(policy))
+}
+
+func currentSyncPolicy() SyncPolicy {
+	return SyncPolicy(atomic.LoadInt32(&syncPolicy))
+}
+
+// syncOnClose reports whether a completed write must be synced
+func syncOnClose() bool {
+	policy := currentSyncPolicy()
+	return policy == SyncOnClose || policy == SyncEveryWrite
+}
+
+// syncCopies reports whether CopyFile must sync its destination
+func syncCopies() bool {
+	return currentSyncPolicy() != SyncNever
+}
+
+// writeFile is os.WriteFile with a Sync before Close when the policy asks for it
+func writeFile(name string, content []byte, perm os.FileMode) error {
+	if !syncOnClose() {
+		return os.WriteFile(name, content, perm)
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SyncDir fsyncs the directory dir, which makes creates, renames and removes
+// of its entries durable. Windows has no directory fsync, SyncDir does nothing
+// there.
+func SyncDir(dir string) (err error) {
+	defer startOp("SyncDir", dir).end(&err)
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	ioWait()
+	f, err := os.Open(longPath(cleanPath(dir)))
+	if err != nil {
+		ioDone()
+		errorPrinter("SyncDir (os.Open): "+err.Error(), dir)
+		return err
+	}
+	err = f.Sync()
+	f.Close()
+	ioDone()
+	if err != nil {
+		errorPrinter("SyncDir (Sync): "+err.Error(), dir)
+	}
+	return err
+}
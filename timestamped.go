@@ -0,0 +1,94 @@
+package GMSFS
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimestampedName returns prefix.YYYYMMDD_hhmm+ext with the current local
+// time, the naming of the GMSFS debug logs (GMSFS.20240131_1542.log). ext
+// includes the dot.
+func TimestampedName(prefix string, ext string) string {
+	return timestampedName(prefix, ext, time.Now())
+}
+
+func timestampedName(prefix string, ext string, t time.Time) string {
+	return prefix + "." + t.Format(timeFlat) + ext
+}
+
+// ParseTimestampedName splits a name built by TimestampedName into its
+// prefix, time and extension. Directories in name are ignored.
+func ParseTimestampedName(name string) (prefix string, t time.Time, ext string, err error) {
+	base := filepath.Base(name)
+	ext = filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	i := len(stem) - len(timeFlat) - 1
+	if i < 0 || stem[i] != '.' {
+		return "", time.Time{}, "", fmt.Errorf("%s is not a timestamped name", name)
+	}
+	t, err = time.ParseInLocation(timeFlat, stem[i+1:], time.Local)
+	if err != nil {
+		return "", time.Time{}, "", fmt.Errorf("%s is not a timestamped name: %w", name, err)
+	}
+	return stem[:i], t, ext, nil
+}
+
+// ListTimestamped returns the files in dir named by TimestampedName with
+// prefix and ext, oldest first
+func ListTimestamped(dir string, prefix string, ext string) ([]string, error) {
+	entries, err := ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type stamped struct {
+		name string
+		t    time.Time
+	}
+	var found []stamped
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		p, t, e, err := ParseTimestampedName(entry.Name)
+		if err != nil || p != prefix || e != ext {
+			continue
+		}
+		found = append(found, stamped{filepath.Join(dir, entry.Name), t})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].t.Before(found[j].t) })
+
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+// PruneTimestamped removes all but the keep newest files of ListTimestamped
+// and returns the removed names
+func PruneTimestamped(dir string, prefix string, ext string, keep int) ([]string, error) {
+	names, err := ListTimestamped(dir, prefix, ext)
+	if err != nil {
+		return nil, err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, name := range names[:len(names)-keep] {
+		if err := Remove(name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
@@ -0,0 +1,41 @@
+package GMSFS
+
+import (
+	"sort"
+)
+
+// TopBySize returns the n largest files below root, largest first, computed
+// from the cached listings. Name of the returned entries is the full path, a
+// negative n returns every file.
+func TopBySize(root string, n int) ([]FileInfo, error) {
+	return topFiles(root, n, func(a, b FileInfo) bool {
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Name < b.Name
+	})
+}
+
+// TopByAge returns the n least recently modified files below root, oldest
+// first, computed from the cached listings
+func TopByAge(root string, n int) ([]FileInfo, error) {
+	return topFiles(root, n, func(a, b FileInfo) bool {
+		if !a.LastModified.Equal(b.LastModified) {
+			return a.LastModified.Before(b.LastModified)
+		}
+		return a.Name < b.Name
+	})
+}
+
+func topFiles(root string, n int, less func(a, b FileInfo) bool) ([]FileInfo, error) {
+	files, err := Find(root, FindOfType(FindFile))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return less(files[i], files[j]) })
+	if n >= 0 && len(files) > n {
+		files = files[:n]
+	}
+	return files, nil
+}
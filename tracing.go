@@ -0,0 +1,34 @@
+package GMSFS
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Tracer creates a span for each filesystem operation. ctx is the context
+// given to the *Context functions, context.Background() for the others. See
+// the otelgmsfs package for an OpenTelemetry implementation.
+type Tracer interface {
+	Start(ctx context.Context, op string, path string) Span
+}
+
+// Span is one traced operation
+type Span interface {
+	SetCacheHit(hit bool)
+	SetBytes(n int64)
+	End(err error)
+}
+
+type tracerHolder struct{ Tracer }
+
+var currentTracerValue atomic.Value
+
+// SetTracer traces every filesystem operation with t, nil turns tracing off
+func SetTracer(t Tracer) {
+	currentTracerValue.Store(tracerHolder{t})
+}
+
+func currentTracer() Tracer {
+	holder, _ := currentTracerValue.Load().(tracerHolder)
+	return holder.Tracer
+}
@@ -0,0 +1,77 @@
+package GMSFS_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inpadi/GMSFS"
+)
+
+// Names a client may send to climb out of the served root. On Windows the
+// \ forms get past path.Clean, which does not see them as separators.
+var escapes = []string{
+	"/../../secret",
+	`/..\..\secret`,
+	`/sub/..\..\..\secret`,
+}
+
+func servedRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "a", "root")
+	writeTree(t, dir, map[string]string{
+		"a/secret":          "secret",
+		"secret":            "secret",
+		"a/root/sub/public": "public",
+	})
+	return root
+}
+
+func TestHandlersStayInRoot(t *testing.T) {
+	root := servedRoot(t)
+	handlers := map[string]http.Handler{
+		"HTTPHandler": GMSFS.HTTPHandler(root),
+		"FileServer":  GMSFS.FileServer(root),
+		"Agent":       GMSFS.NewAgent(root),
+	}
+	for name, handler := range handlers {
+		for _, escape := range escapes {
+			target := "/" + url.PathEscape(escape[1:])
+			if name == "Agent" {
+				target = "/file?path=" + url.QueryEscape(escape)
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if strings.Contains(rec.Body.String(), "secret") && rec.Code == http.StatusOK {
+				t.Errorf("%s: GET %s served the file outside the root", name, target)
+			}
+		}
+	}
+}
+
+func TestFSStaysInRoot(t *testing.T) {
+	root := servedRoot(t)
+	for _, escape := range escapes {
+		if f, err := GMSFS.NewWebDAVFS(root).OpenFile(context.Background(), escape, os.O_RDONLY, 0); err == nil {
+			content, _ := io.ReadAll(f)
+			f.Close()
+			if string(content) == "secret" {
+				t.Errorf("WebDAVFS.OpenFile(%q) opened the file outside the root", escape)
+			}
+		}
+		if content, err := GMSFS.DirFS(root).ReadFile(escape[1:]); err == nil && string(content) == "secret" {
+			t.Errorf("DirFS.ReadFile(%q) read the file outside the root", escape[1:])
+		}
+		if content, err := (&GMSFS.LocalBackend{Root: root}).ReadFile(escape); err == nil && string(content) == "secret" {
+			t.Errorf("LocalBackend.ReadFile(%q) read the file outside the root", escape)
+		}
+	}
+}
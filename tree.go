@@ -0,0 +1,108 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TreeNode is one entry of a directory tree built by TreeNodes. Info.Name is
+// the full path, Name just the base name.
+type TreeNode struct {
+	Name     string
+	Info     FileInfo
+	Children []*TreeNode
+}
+
+// TreeNodes builds the tree below root from cached listings. The entries of
+// root are depth 1, a negative maxDepth descends all the way. Directories are
+// listed before files and symlinked directories are not followed.
+func TreeNodes(root string, maxDepth int) (*TreeNode, error) {
+	root = cleanPath(root)
+	info, err := Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	info.Name = root
+	info.Contents = nil
+
+	node := &TreeNode{Name: root, Info: info}
+	if !info.IsDir {
+		return node, nil
+	}
+	if err := buildTree(node, 1, maxDepth); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func buildTree(node *TreeNode, depth int, maxDepth int) error {
+	if maxDepth >= 0 && depth > maxDepth {
+		return nil
+	}
+
+	contents, err := ReadDir(node.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range contents {
+		entry.Name = filepath.Join(node.Info.Name, entry.Name)
+		entry.Contents = nil
+		child := &TreeNode{Name: filepath.Base(entry.Name), Info: entry}
+
+		if entry.IsDir && entry.Mode&os.ModeSymlink == 0 {
+			if err := buildTree(child, depth+1, maxDepth); err != nil {
+				errorPrinter("Tree (ReadDir): "+err.Error(), entry.Name)
+			}
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.Info.IsDir != b.Info.IsDir {
+			return a.Info.IsDir
+		}
+		return a.Name < b.Name
+	})
+	return nil
+}
+
+// Tree renders the tree below root like the tree command, directories get a
+// trailing slash.
+//
+//	/srv/data/
+//	├── logs/
+//	│   └── app.log
+//	└── config.json
+func Tree(root string, maxDepth int) (string, error) {
+	node, err := TreeNodes(root, maxDepth)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(treeLabel(node) + "\n")
+	renderTree(&sb, node, "")
+	return sb.String(), nil
+}
+
+func renderTree(sb *strings.Builder, node *TreeNode, indent string) {
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+		sb.WriteString(indent + branch + treeLabel(child) + "\n")
+		renderTree(sb, child, indent+next)
+	}
+}
+
+func treeLabel(node *TreeNode) string {
+	if node.Info.IsDir && !strings.HasSuffix(node.Name, string(os.PathSeparator)) {
+		return node.Name + "/"
+	}
+	return node.Name
+}
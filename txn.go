@@ -0,0 +1,275 @@
+package GMSFS
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Txn stages file writes, renames and removals and applies them together on
+// Commit. Replaced and removed files are kept in the staging area until the
+// commit has gone through, so a failed commit puts them back (best effort, a
+// crash half way leaves the journal and backups in the staging directory).
+// A transaction begun in dry-run mode stages nothing on disk, its Commit
+// only records the planned operations.
+type Txn struct {
+	dir    string
+	ops    []txnOp
+	done   bool
+	dryRun bool
+}
+
+type txnOp struct {
+	Op     string `json:"op"` // "write", "rename" or "remove"
+	Path   string `json:"path"`
+	From   string `json:"from,omitempty"`   // Source of a rename
+	Staged string `json:"staged,omitempty"` // Staged content of a write
+	Backup string `json:"backup,omitempty"` // Where the replaced file was moved
+}
+
+// BeginTxn starts a transaction staging into a new directory below dir. dir
+// must be on the same filesystem as the files changed, so commits are renames.
+func BeginTxn(dir string) (_ *Txn, err error) {
+	defer startOp("BeginTxn", dir).end(&err)
+	if err := injectedFault("BeginTxn", dir); err != nil {
+		return nil, err
+	}
+	if dryRunning() {
+		return &Txn{dryRun: true}, nil
+	}
+
+	ioWait()
+	staging, err := os.MkdirTemp(longPath(cleanPath(dir)), ".txn-*")
+	ioDone()
+	if err != nil {
+		errorPrinter("BeginTxn (os.MkdirTemp): "+err.Error(), dir)
+		return nil, err
+	}
+	return &Txn{dir: staging}, nil
+}
+
+// WriteFile stages content for name, the file itself is written on Commit
+func (t *Txn) WriteFile(name string, content []byte, perm os.FileMode) (err error) {
+	defer startOp("Txn.WriteFile", name).end(&err)
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	if err := injectedFault("Txn.WriteFile", name); err != nil {
+		return err
+	}
+	if t.dryRun {
+		t.ops = append(t.ops, txnOp{Op: "write", Path: cleanPath(name)})
+		return nil
+	}
+
+	staged := filepath.Join(t.dir, fmt.Sprintf("data-%d", len(t.ops)))
+	ioWait()
+	defer ioDone()
+	f, err := os.OpenFile(staged, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	t.ops = append(t.ops, txnOp{Op: "write", Path: cleanPath(name), Staged: staged})
+	return nil
+}
+
+// Rename stages renaming oldName to newName
+func (t *Txn) Rename(oldName, newName string) error {
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	t.ops = append(t.ops, txnOp{Op: "rename", Path: cleanPath(newName), From: cleanPath(oldName)})
+	return nil
+}
+
+// Remove stages removing name
+func (t *Txn) Remove(name string) error {
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	t.ops = append(t.ops, txnOp{Op: "remove", Path: cleanPath(name)})
+	return nil
+}
+
+// Commit applies the staged operations in order. When one fails the ones
+// already applied are undone and the error is returned. The cache is only
+// updated for the paths touched once the outcome is known.
+func (t *Txn) Commit() (err error) {
+	defer startOp("Txn.Commit", t.dir).end(&err)
+	if t.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	if err := injectedFault("Txn.Commit", t.dir); err != nil {
+		return err
+	}
+	if t.dryRun || dryRunning() {
+		for _, op := range t.ops {
+			if op.Op == "rename" {
+				planned(op.Op, op.From, op.Path)
+			} else {
+				planned(op.Op, op.Path, "")
+			}
+		}
+		return t.Rollback()
+	}
+	t.done = true
+
+	for i := range t.ops {
+		t.ops[i].Backup = filepath.Join(t.dir, fmt.Sprintf("backup-%d", i))
+	}
+	if err := t.writeJournal(); err != nil {
+		t.removeStaging()
+		return err
+	}
+
+	var applied int
+	for applied = 0; applied < len(t.ops); applied++ {
+		if err = t.apply(&t.ops[applied]); err != nil {
+			errorPrinter("Txn.Commit ("+t.ops[applied].Op+"): "+err.Error(), t.ops[applied].Path)
+			break
+		}
+	}
+
+	if err != nil {
+		for i := applied - 1; i >= 0; i-- {
+			if uerr := t.undo(t.ops[i]); uerr != nil {
+				errorPrinter("Txn.Commit (undo): "+uerr.Error(), t.ops[i].Path)
+				t.invalidate()
+				return fmt.Errorf("commit failed: %v, rollback incomplete, see %s: %v", err, t.dir, uerr)
+			}
+		}
+		t.invalidate()
+		t.removeStaging()
+		return err
+	}
+
+	t.invalidate()
+	t.audit()
+	t.removeStaging()
+	return nil
+}
+
+// Rollback discards the staged operations of a transaction not committed yet
+func (t *Txn) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	return t.removeStaging()
+}
+
+func (t *Txn) removeStaging() error {
+	if t.dir == "" {
+		return nil // Dry run, nothing was staged
+	}
+	ioWait()
+	defer ioDone()
+	return os.RemoveAll(longPath(t.dir))
+}
+
+func (t *Txn) writeJournal() error {
+	journal, err := json.MarshalIndent(t.ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	ioWait()
+	defer ioDone()
+	return os.WriteFile(filepath.Join(t.dir, "journal.json"), journal, 0644)
+}
+
+// apply performs op, moving a file it replaces or removes to op.Backup. When
+// there was nothing to replace op.Backup is cleared.
+func (t *Txn) apply(op *txnOp) error {
+	ioWait()
+	defer ioDone()
+	if err := os.Rename(longPath(op.Path), longPath(op.Backup)); err != nil {
+		if !os.IsNotExist(err) || op.Op == "remove" {
+			return err
+		}
+		op.Backup = ""
+	}
+
+	var err error
+	switch op.Op {
+	case "write":
+		err = os.Rename(op.Staged, longPath(op.Path))
+	case "rename":
+		err = os.Rename(longPath(op.From), longPath(op.Path))
+	}
+	if err != nil && op.Backup != "" {
+		os.Rename(longPath(op.Backup), longPath(op.Path))
+	}
+	return err
+}
+
+func (t *Txn) undo(op txnOp) error {
+	ioWait()
+	defer ioDone()
+	switch op.Op {
+	case "rename":
+		if err := os.Rename(longPath(op.Path), longPath(op.From)); err != nil {
+			return err
+		}
+	case "write":
+		if err := os.Remove(longPath(op.Path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if op.Backup != "" {
+		return os.Rename(longPath(op.Backup), longPath(op.Path))
+	}
+	return nil
+}
+
+// invalidate drops the cached entries of every path the transaction touched
+func (t *Txn) invalidate() {
+	dirs := map[string]bool{}
+	for _, op := range t.ops {
+		for _, name := range []string{op.Path, op.From} {
+			if name == "" {
+				continue
+			}
+			CacheDelete(cacheKey(name))
+			publishInvalidation(name)
+			dirs[filepath.Dir(name)] = true
+		}
+	}
+	for dir := range dirs {
+		CacheDelete(cacheKey(dir))
+	}
+}
+
+// audit records the committed operations, the backups still hold the
+// replaced files at this point
+func (t *Txn) audit() {
+	if !auditing() {
+		return
+	}
+	for _, op := range t.ops {
+		var before int64
+		if op.Backup != "" {
+			before = auditSize(op.Backup)
+		}
+		switch op.Op {
+		case "write":
+			audit("write", op.Path, "", auditSize(op.Path)-before)
+		case "rename":
+			audit("rename", op.From, op.Path, -before)
+		case "remove":
+			audit("remove", op.Path, "", -before)
+		}
+	}
+}
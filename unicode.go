@@ -0,0 +1,31 @@
+package GMSFS
+
+import (
+	"runtime"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUnicode makes cache keys NFC normalized. HFS+ and APFS return NFD
+// names while callers usually pass NFC, which without normalization gives
+// cache misses and duplicate entries for the same file.
+var NormalizeUnicode = runtime.GOOS == "darwin"
+
+// cacheKey derives the cache key for a path
+func cacheKey(name string) string {
+	return normalizeKey(strings.ToLower(absPath(cleanPath(name))))
+}
+
+func normalizeKey(key string) string {
+	if NormalizeUnicode {
+		return norm.NFC.String(key)
+	}
+	return key
+}
+
+// CanonicalName returns name in Unicode normalization form C, the form to use
+// when comparing or storing names coming from different filesystems
+func CanonicalName(name string) string {
+	return norm.NFC.String(name)
+}
@@ -0,0 +1,165 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DivergenceMissing  = "missing"  // cached as existing, gone on disk
+	DivergenceNegative = "negative" // cached as not existing, present on disk
+	DivergenceMismatch = "mismatch" // size, mtime or type differ
+	DivergenceListing  = "listing"  // cached directory contents differ
+	DivergenceOrphaned = "orphaned" // not listed in its cached parent directory
+)
+
+// Divergence is a cache entry that does not match the filesystem
+type Divergence struct {
+	Key    string
+	Kind   string
+	Cached FileInfo
+	Actual FileInfo
+}
+
+// Report is the result of ValidateCache or RepairCache
+type Report struct {
+	Checked     int
+	Divergences []Divergence
+	Repaired    int
+}
+
+// ValidateCache compares every cached entry at or below root with os.Stat and
+// os.ReadDir and reports the entries that diverge from the filesystem.
+func ValidateCache(root string) (Report, error) {
+	return validateCache(root, false)
+}
+
+// RepairCache works like ValidateCache and also drops or refreshes every
+// divergent entry.
+func RepairCache(root string) (Report, error) {
+	return validateCache(root, true)
+}
+
+func validateCache(root string, repair bool) (Report, error) {
+	var report Report
+
+	rootKey := cacheKey(root)
+	if _, err := os.Stat(root); err != nil && !os.IsNotExist(err) {
+		return report, err
+	}
+	prefix := strings.TrimSuffix(rootKey, string(os.PathSeparator)) + string(os.PathSeparator)
+
+	keys := cacheKeys.Keys()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key != rootKey && !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		item, found := cache.Get(key)
+		if !found {
+			continue // Still buffered, see cacheItems
+		}
+		cached := item.Value.(FileInfo)
+		if item.Listed {
+			cached = resolveChildren(item, cached)
+		}
+		report.Checked++
+
+		name, stat, err := diskPath(key)
+		var divergence *Divergence
+		switch {
+		case err != nil && os.IsNotExist(err):
+			if cached.Exists {
+				divergence = &Divergence{Key: key, Kind: DivergenceMissing, Cached: cached}
+			}
+		case err != nil:
+			return report, err
+		case !cached.Exists:
+			divergence = &Divergence{Key: key, Kind: DivergenceNegative, Cached: cached, Actual: fileInfoFromOS(stat)}
+		case stat.IsDir() != cached.IsDir || (!stat.IsDir() && stat.Size() != cached.Size) ||
+			(!cached.LastModified.IsZero() && !stat.ModTime().Equal(cached.LastModified)):
+			divergence = &Divergence{Key: key, Kind: DivergenceMismatch, Cached: cached, Actual: fileInfoFromOS(stat)}
+		case stat.IsDir() && cached.Contents != nil && !sameListing(name, cached.Contents):
+			divergence = &Divergence{Key: key, Kind: DivergenceListing, Cached: cached, Actual: fileInfoFromOS(stat)}
+		case key != rootKey && orphaned(key):
+			divergence = &Divergence{Key: key, Kind: DivergenceOrphaned, Cached: cached, Actual: fileInfoFromOS(stat)}
+		}
+
+		if divergence == nil {
+			continue
+		}
+		report.Divergences = append(report.Divergences, *divergence)
+
+		if repair {
+			CacheDelete(key)
+			CacheDelete(filepath.Dir(key))
+			if err == nil {
+				UpdateFileInfo(name)
+			}
+			report.Repaired++
+		}
+	}
+
+	return report, nil
+}
+
+// diskPath finds the on-disk name for a (lower case) cache key. On case
+// sensitive filesystems the key itself may not exist, so it is resolved with
+// RealCase.
+func diskPath(key string) (string, os.FileInfo, error) {
+	stat, err := os.Stat(key)
+	if err == nil {
+		return key, stat, err
+	}
+
+	if name, err2 := RealCase(key); err2 == nil {
+		if stat, err2 := os.Stat(name); err2 == nil {
+			return name, stat, nil
+		}
+	}
+
+	return key, nil, err
+}
+
+func sameListing(dir string, contents []FileInfo) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != len(contents) {
+		return false
+	}
+
+	cached := make(map[string]FileInfo, len(contents))
+	for _, c := range contents {
+		cached[c.Name] = c
+	}
+	for _, entry := range entries {
+		c, ok := cached[entry.Name()]
+		if !ok || c.IsDir != entry.IsDir() {
+			return false
+		}
+		if !c.IsDir {
+			if stat, err := entry.Info(); err == nil && stat.Size() != c.Size {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// orphaned reports whether key is missing from the cached listing of its parent
+func orphaned(key string) bool {
+	parent, found := cache.Get(filepath.Dir(key))
+	if !found || !parent.Listed {
+		return false
+	}
+
+	for _, childKey := range parent.Children {
+		if childKey == key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package GMSFS
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WaitForFile returns the info of path once it exists, waiting until it is
+// created or ctx is done. A create through GMSFS in this process is noticed
+// right away, other processes' by looking at the disk every poll
+// (WaitPollInterval when 0). The polls bypass the cache, so a cached "does
+// not exist" entry cannot hide the file, and do not add negative entries of
+// their own; once the file is there its cached entry is refreshed.
+func WaitForFile(ctx context.Context, path string, poll time.Duration) (_ FileInfo, err error) {
+	defer startOpContext(ctx, "WaitForFile", path).end(&err)
+	path = cleanPath(path)
+	if poll <= 0 {
+		poll = WaitPollInterval
+	}
+
+	// Subscribe before looking, so a create in between is not missed
+	changes, cancel := SubscribeChanges(16)
+	defer cancel()
+
+	key := cacheKey(path)
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		ioWait()
+		_, err := os.Stat(longPath(path))
+		ioDone()
+		if err == nil {
+			if err := UpdateFileInfo(path); err != nil {
+				return FileInfo{}, err
+			}
+			return Stat(path)
+		}
+		if !os.IsNotExist(err) {
+			return FileInfo{}, err
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return FileInfo{}, ctx.Err()
+			case <-ticker.C:
+				break wait
+			case change := <-changes:
+				if cacheKey(change.Path) == key || (change.NewPath != "" && cacheKey(change.NewPath) == key) {
+					break wait
+				}
+			}
+		}
+	}
+}
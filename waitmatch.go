@@ -0,0 +1,89 @@
+package GMSFS
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WaitPollInterval is how often the Wait* functions look at the disk
+var WaitPollInterval = 500 * time.Millisecond
+
+// WatchMatch is Watch reduced to created and modified entries of dir whose
+// base name matches pattern (a case insensitive glob).
+func WatchMatch(ctx context.Context, dir string, pattern string, interval time.Duration) (<-chan WatchEvent, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	changes, err := Watch(ctx, dir, interval)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		defer cancel()
+
+		for change := range changes {
+			if change.Op == WatchRemove || !matchName(pattern, change.Info.Name) {
+				continue
+			}
+			select {
+			case events <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// WaitForMatch returns the path of an entry in dir matching pattern, waiting
+// until one appears or ctx is done. An entry that already exists is returned
+// right away.
+func WaitForMatch(ctx context.Context, dir string, pattern string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before looking, so nothing created in between is missed
+	events, err := WatchMatch(ctx, dir, pattern, WaitPollInterval)
+	if err != nil {
+		return "", err
+	}
+
+	dir = cleanPath(dir)
+	if listing, err := scanDir(dir); err == nil {
+		var names []string
+		for name := range listing {
+			if matchName(pattern, name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return filepath.Join(dir, names[0]), nil
+		}
+	}
+
+	select {
+	case event, ok := <-events:
+		if ok {
+			return event.Path, nil
+		}
+		return "", ctx.Err()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func matchName(pattern string, name string) bool {
+	matched, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return matched
+}
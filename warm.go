@@ -0,0 +1,137 @@
+package GMSFS
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Warm walks the tree below root with workers goroutines reading directories
+// concurrently (1 when less) and caches the Stat and ReadDir results for every entry, so traffic
+// after a deploy starts with a hot cache. maxDepth 0 only warms root itself,
+// a negative maxDepth walks the whole tree.
+func Warm(root string, maxDepth, workers int) error {
+	root = cleanPath(root)
+	if workers < 1 {
+		workers = 1
+	}
+
+	rootInfo, err := Stat(root)
+	if err != nil {
+		errorPrinter("Warm (Stat): "+err.Error(), root)
+		return err
+	}
+	if !rootInfo.IsDir {
+		return nil
+	}
+
+	// A fixed pool of workers taking directories from a queue, a wide tree
+	// must not start a goroutine for every directory at once
+	type warmTask struct {
+		dir   string
+		depth int
+	}
+	var mutex sync.Mutex
+	wake := sync.NewCond(&mutex)
+	queue := []warmTask{{dir: root}}
+	busy := 0
+
+	work := func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for {
+			for len(queue) == 0 && busy > 0 {
+				wake.Wait()
+			}
+			if len(queue) == 0 {
+				// Nothing queued and nobody left to queue more
+				wake.Broadcast()
+				return
+			}
+			task := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			busy++
+
+			mutex.Unlock()
+			contents := warmDir(task.dir)
+			mutex.Lock()
+
+			busy--
+			if maxDepth < 0 || task.depth < maxDepth {
+				for _, entry := range contents {
+					if entry.IsDir && entry.Mode&os.ModeSymlink == 0 {
+						queue = append(queue, warmTask{dir: filepath.Join(task.dir, entry.Name), depth: task.depth + 1})
+					}
+				}
+			}
+			wake.Broadcast()
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// warmDir caches dir and each of its entries from a single directory read
+func warmDir(dir string) []FileInfo {
+	ioWait()
+	stat, err := os.Stat(longPath(dir))
+	ioDone()
+	if err != nil {
+		logf("Warm (os.Stat): %v", err)
+		return nil
+	}
+	ioWait()
+	entries, err := os.ReadDir(longPath(dir))
+	ioDone()
+	if err != nil {
+		logf("Warm (os.ReadDir): %v", err)
+		return nil
+	}
+
+	now := clockNow()
+	contents := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		entryStat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		info := FileInfo{
+			Exists:       true,
+			Size:         entryStat.Size(),
+			Mode:         entryStat.Mode(),
+			LastModified: entryStat.ModTime(),
+			IsDir:        entryStat.IsDir(),
+			Name:         entryStat.Name(),
+			CacheTime:    now,
+		}
+		contents = append(contents, info)
+		// Directories get their own entry (with Contents) when they are visited,
+		// an entry without Contents would make ReadDir report them as empty
+		if !info.IsDir {
+			CacheAdd(cacheKey(filepath.Join(dir, info.Name)), info)
+		}
+	}
+
+	CacheAdd(cacheKey(dir), FileInfo{
+		Exists:       true,
+		IsDir:        true,
+		Mode:         stat.Mode(),
+		LastModified: stat.ModTime(),
+		Name:         filepath.Base(dir),
+		Contents:     contents,
+		CacheTime:    now,
+	})
+
+	return contents
+}
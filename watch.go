@@ -0,0 +1,130 @@
+package GMSFS
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	WatchCreate = "create"
+	WatchModify = "modify"
+	WatchRemove = "remove"
+)
+
+// WatchEvent describes a change noticed in a watched directory
+type WatchEvent struct {
+	Op   string
+	Path string
+	Info FileInfo
+}
+
+// Watch polls dir every interval and reports entries that were created,
+// modified or removed, refreshing the cached listing whenever it changed.
+// The channel is closed when ctx is done.
+func Watch(ctx context.Context, dir string, interval time.Duration) (<-chan WatchEvent, error) {
+	dir = cleanPath(dir)
+	previous, err := scanDir(dir)
+	if err != nil {
+		errorPrinter("Watch (scanDir): "+err.Error(), dir)
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := scanDir(dir)
+			if os.IsNotExist(err) {
+				// The directory itself disappeared, its entries with it
+				current = map[string]FileInfo{}
+			} else if err != nil {
+				// Temporarily unreadable (EACCES, EIO, EMFILE): nothing is
+				// known to have changed, look again on the next tick
+				logf("Watch (scanDir): %v", err)
+				continue
+			}
+
+			changes := diffListings(dir, previous, current)
+			if len(changes) > 0 {
+				CacheDelete(cacheKey(dir))
+				UpdateDirectoryContents(dir)
+				for _, change := range changes {
+					CacheDelete(cacheKey(change.Path))
+				}
+			}
+			previous = current
+
+			for _, change := range changes {
+				select {
+				case events <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// scanDir reads dir straight from disk, as the cache cannot see external changes
+func scanDir(dir string) (map[string]FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := make(map[string]FileInfo, len(entries))
+	for _, entry := range entries {
+		stat, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		listing[entry.Name()] = FileInfo{
+			Exists:       true,
+			Size:         stat.Size(),
+			Mode:         stat.Mode(),
+			LastModified: stat.ModTime(),
+			IsDir:        stat.IsDir(),
+			Name:         entry.Name(),
+			CacheTime:    clockNow(),
+		}
+	}
+
+	return listing, nil
+}
+
+func diffListings(dir string, previous, current map[string]FileInfo) []WatchEvent {
+	var changes []WatchEvent
+	for name, info := range current {
+		old, ok := previous[name]
+		switch {
+		case !ok:
+			changes = append(changes, WatchEvent{Op: WatchCreate, Path: filepath.Join(dir, name), Info: info})
+		case old.Size != info.Size || !old.LastModified.Equal(info.LastModified) || old.IsDir != info.IsDir:
+			changes = append(changes, WatchEvent{Op: WatchModify, Path: filepath.Join(dir, name), Info: info})
+		}
+	}
+	for name, info := range previous {
+		if _, ok := current[name]; !ok {
+			info.Exists = false
+			changes = append(changes, WatchEvent{Op: WatchRemove, Path: filepath.Join(dir, name), Info: info})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	return changes
+}
@@ -0,0 +1,158 @@
+package GMSFS
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVFS implements webdav.FileSystem on top of the cached functions, so
+// changes made over WebDAV keep the cache coherent.
+type WebDAVFS struct {
+	Root string
+}
+
+func NewWebDAVFS(root string) *WebDAVFS {
+	return &WebDAVFS{Root: cleanPath(root)}
+}
+
+// WebDAVHandler serves root over WebDAV with an in-memory lock system
+func WebDAVHandler(root string) http.Handler {
+	return &webdav.Handler{
+		FileSystem: NewWebDAVFS(root),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+func (w *WebDAVFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	fullName, err := joinRoot(w.Root, name)
+	if err != nil {
+		return err
+	}
+	return Mkdir(fullName, perm)
+}
+
+func (w *WebDAVFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	fullName, err := joinRoot(w.Root, name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := OpenFile(fullName, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webdavFile{File: file, path: fullName, written: flag&(os.O_WRONLY|os.O_RDWR) != 0}, nil
+}
+
+func (w *WebDAVFS) RemoveAll(ctx context.Context, name string) error {
+	fullName, err := joinRoot(w.Root, name)
+	if err != nil {
+		return err
+	}
+	return RemoveAll(fullName)
+}
+
+func (w *WebDAVFS) Rename(ctx context.Context, oldName, newName string) error {
+	fullOld, err := joinRoot(w.Root, oldName)
+	if err != nil {
+		return err
+	}
+	fullNew, err := joinRoot(w.Root, newName)
+	if err != nil {
+		return err
+	}
+	return Rename(fullOld, fullNew)
+}
+
+func (w *WebDAVFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fullName, err := joinRoot(w.Root, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := Stat(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	return osFileInfo{info}, nil
+}
+
+type webdavFile struct {
+	*os.File
+	path    string
+	written bool
+	entries []FileInfo
+	offset  int
+}
+
+func (f *webdavFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if f.entries == nil {
+		contents, err := ReadDir(f.path)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = append([]FileInfo{}, contents...)
+	}
+
+	remaining := f.entries[f.offset:]
+	if count > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+		if count < len(remaining) {
+			remaining = remaining[:count]
+		}
+	}
+	f.offset += len(remaining)
+
+	infos := make([]fs.FileInfo, 0, len(remaining))
+	for _, entry := range remaining {
+		infos = append(infos, osFileInfo{entry})
+	}
+	return infos, nil
+}
+
+func (f *webdavFile) Stat() (fs.FileInfo, error) {
+	info, err := Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return osFileInfo{info}, nil
+}
+
+func (f *webdavFile) Close() error {
+	err := f.File.Close()
+	if f.written {
+		UpdateFileInfo(f.path)
+		CacheDelete(cacheKey(filepath.Dir(f.path)))
+	}
+
+	return err
+}
+
+// osFileInfo adapts a cached FileInfo to the fs.FileInfo interface
+type osFileInfo struct {
+	info FileInfo
+}
+
+func (o osFileInfo) Name() string       { return o.info.Name }
+func (o osFileInfo) Size() int64        { return o.info.Size }
+func (o osFileInfo) ModTime() time.Time { return o.info.LastModified }
+func (o osFileInfo) IsDir() bool        { return o.info.IsDir }
+func (o osFileInfo) Sys() interface{}   { return nil }
+
+func (o osFileInfo) Mode() fs.FileMode {
+	// Directory entries built from listings do not always carry the mode bits
+	if o.info.IsDir {
+		return o.info.Mode | fs.ModeDir
+	}
+	return o.info.Mode
+}
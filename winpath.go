@@ -0,0 +1,106 @@
+package GMSFS
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// WindowsPaths makes path cleaning understand drive letters (C:\) and UNC
+// volumes (\\server\share), accepting both / and \ as separators. It is on by
+// default when running on Windows.
+var WindowsPaths = runtime.GOOS == "windows"
+
+// maxPath is the length from which Windows paths need the \\?\ prefix. It is
+// MAX_PATH minus room for an 8.3 file name, the limit for creating directories.
+const maxPath = 248
+
+// mountPrefixSeparator marks an explicit mount prefix, "<mount>::<path>". The
+// mount part is dropped and only <path> is used for file operations and keys.
+const mountPrefixSeparator = "::"
+
+func cleanPath(name string) string {
+	name = trimMountPrefix(name)
+
+	if WindowsPaths {
+		return cleanWindowsPath(name)
+	}
+	return filepath.Clean(name)
+}
+
+// trimMountPrefix drops a leading "<mount>::". The mount must come before any
+// separator or drive colon, so a "::" further along, like the stream of
+// "C:\dir\file::$DATA" or one below a device or UNC prefix, is kept.
+func trimMountPrefix(name string) string {
+	i := strings.Index(name, mountPrefixSeparator)
+	if i <= 0 || strings.ContainsAny(name[:i], `/\:`) {
+		return name
+	}
+	return name[i+len(mountPrefixSeparator):]
+}
+
+// cleanWindowsPath cleans name with Windows semantics independent of the host
+func cleanWindowsPath(name string) string {
+	name = strings.ReplaceAll(name, "/", `\`)
+	switch {
+	case strings.HasPrefix(name, `\\?\UNC\`):
+		name = `\\` + name[len(`\\?\UNC\`):]
+	case strings.HasPrefix(name, `\\?\`):
+		name = name[len(`\\?\`):]
+	}
+	volume := windowsVolume(name)
+	rest := strings.ReplaceAll(name[len(volume):], `\`, "/")
+
+	rooted := strings.HasPrefix(rest, "/")
+	rest = path.Clean(rest)
+	if rest == "." && volume != "" {
+		rest = ""
+	}
+	if rest == "/" && !rooted {
+		rest = ""
+	}
+
+	return volume + strings.ReplaceAll(rest, "/", `\`)
+}
+
+// windowsVolume returns the drive ("C:") or UNC volume ("\\server\share") at
+// the start of name, which must use \ as separator
+func windowsVolume(name string) string {
+	if len(name) >= 2 && name[1] == ':' && isDriveLetter(name[0]) {
+		return name[:2]
+	}
+
+	if !strings.HasPrefix(name, `\\`) || strings.HasPrefix(name, `\\\`) {
+		return ""
+	}
+	parts := strings.SplitN(name[2:], `\`, 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return `\\` + parts[0] + `\` + parts[1]
+}
+
+func isDriveLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// longPath adds the \\?\ prefix to paths too long for the Win32 API, so deeply
+// nested trees can be used. Other paths and platforms are returned unchanged.
+func longPath(name string) string {
+	if runtime.GOOS != "windows" || strings.HasPrefix(name, `\\?\`) {
+		return name
+	}
+	if len(name) < maxPath && filepath.IsAbs(name) {
+		return name
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil || len(abs) < maxPath {
+		return name
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}